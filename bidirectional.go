@@ -0,0 +1,419 @@
+package badstudent
+
+// BiNetwork processes whole sequences both forwards and backwards, so that the outputs at each time
+// step can depend on the inputs that come after it, as well as those before it. This is useful for
+// tasks such as sequence labeling. A BiNetwork is created by Bidirectional.
+type BiNetwork struct {
+	forwards, backwards, head *Network
+}
+
+// BiDoesNotFitError is the equivalent of DoesNotFitError for BiNetworks.
+type BiDoesNotFitError struct {
+	TrainContext
+
+	Net *BiNetwork
+	D   Datum
+}
+
+func (err BiDoesNotFitError) Error() string {
+	return doesNotFit(err.TrainContext, err.Net.InputSize(), err.Net.OutputSize(), err.D)
+}
+
+// Bidirectional combines three finalized Networks into a BiNetwork. 'forwards' is run over each
+// sequence in order, and 'backwards' over the same sequence in reverse. At each time step, the
+// outputs of the two are concatenated (forwards first) and given as the inputs to 'head', whose
+// outputs are the outputs of the BiNetwork. Any sub-graph may be used for each direction, though
+// they will typically be recurrent; head may also be recurrent, but usually is not.
+//
+// Because the outputs at each time step depend on the entire sequence, a BiNetwork buffers each
+// sequence until it has ended (as given by Sequential.SetEnded) before evaluating it, and then
+// gives the outputs for every time step, aligned with the inputs. While training, deltas are
+// backpropagated from head through both directions, so all three Networks are trained together.
+//
+// The Networks keep their own Optimizers, HyperParameters and clipping thresholds, and must be saved
+// and loaded separately. Once combined, head will calculate the deltas of its inputs, and all three
+// are treated as recurrent (see (*Node).Recurrent), so none of the Networks should be trained on
+// their own.
+//
+// For sequences that are given as a single input with a fixed length, see the Bidirectional Layer
+// in package operators.
+//
+// Bidirectional has several error conditions:
+//
+//	(0) If any of the Networks are nil: type NilArgError;
+//	(1) If any of the Networks have not been finalized: ErrNetNotFinalized;
+//	(2) If the same Network is given more than once: ErrSharedNetwork;
+//	(3) If the inputs to forwards and backwards have different sizes, or if the inputs to head
+//	    are not the size of both of their outputs: type SizeMismatchError.
+func Bidirectional(forwards, backwards, head *Network) (*BiNetwork, error) {
+	if forwards == nil {
+		return nil, NilArgError{"Forwards Network"}
+	} else if backwards == nil {
+		return nil, NilArgError{"Backwards Network"}
+	} else if head == nil {
+		return nil, NilArgError{"Head Network"}
+	}
+
+	b := &BiNetwork{forwards, backwards, head}
+	for _, net := range b.networks() {
+		if net.stat < finalized {
+			return nil, ErrNetNotFinalized
+		}
+	}
+
+	if forwards == backwards || forwards == head || backwards == head {
+		return nil, ErrSharedNetwork
+	} else if backwards.InputSize() != forwards.InputSize() {
+		return nil, SizeMismatchError{forwards.InputSize(), backwards.InputSize(), "backwards Network inputs"}
+	} else if size := forwards.OutputSize() + backwards.OutputSize(); head.InputSize() != size {
+		return nil, SizeMismatchError{size, head.InputSize(), "head Network inputs"}
+	}
+
+	head.inputDeltas = true
+	head.setDeltaNeeds()
+
+	// each Network is evaluated over the whole sequence before backpropagating, so they must all
+	// replay their time steps in the same way as recurrent Networks, instead of evaluating them
+	// again. This is the same as the Networks having delay.
+	for _, net := range b.networks() {
+		net.hasDelay = true
+	}
+
+	b.clear()
+	return b, nil
+}
+
+// networks returns the Networks that make up the BiNetwork
+func (b *BiNetwork) networks() []*Network {
+	return []*Network{b.forwards, b.backwards, b.head}
+}
+
+// InputSize returns the number of input values at each time step.
+func (b *BiNetwork) InputSize() int {
+	return b.forwards.InputSize()
+}
+
+// OutputSize returns the number of output values at each time step.
+func (b *BiNetwork) OutputSize() int {
+	return b.head.OutputSize()
+}
+
+// fits returns whether or not the Datum can be given to the BiNetwork as a single time step
+func (b *BiNetwork) fits(d Datum) bool {
+	return len(d.Inputs) == b.InputSize() && (len(d.Outputs) == 0 || len(d.Outputs) == b.OutputSize())
+}
+
+// clear flushes all of the Networks, so that the next sequence is independent of the last
+func (b *BiNetwork) clear() {
+	for _, net := range b.networks() {
+		net.ClearDelays()
+	}
+}
+
+// GetOutputs returns the outputs of the BiNetwork at each time step of a sequence, where inputs[t]
+// are the inputs at time step t. If the inputs at any time step are not the size given by
+// InputSize, GetOutputs will return type SizeMismatchError.
+func (b *BiNetwork) GetOutputs(inputs [][]float64) ([][]float64, error) {
+	for _, in := range inputs {
+		if len(in) != b.InputSize() {
+			return nil, SizeMismatchError{b.InputSize(), len(in), "inputs"}
+		}
+	}
+
+	outs := b.evaluate(inputs)
+	b.clear()
+
+	return outs, nil
+}
+
+// evaluate runs the Networks over the given sequence, returning the outputs at each time step. It
+// assumes that the inputs fit and that the Networks have been cleared.
+func (b *BiNetwork) evaluate(inputs [][]float64) [][]float64 {
+	// the errors from GetOutputs can be ignored here for the same reasons as in (*Network).Train()
+	fOuts := make([][]float64, len(inputs))
+	for t := range inputs {
+		fOuts[t], _ = b.forwards.GetOutputs(inputs[t])
+	}
+
+	bOuts := make([][]float64, len(inputs))
+	for t := len(inputs) - 1; t >= 0; t-- {
+		bOuts[t], _ = b.backwards.GetOutputs(inputs[t])
+	}
+
+	outs := make([][]float64, len(inputs))
+	for t := range inputs {
+		outs[t], _ = b.head.GetOutputs(append(fOuts[t], bOuts[t]...))
+	}
+
+	return outs
+}
+
+// train evaluates the BiNetwork over the given sequence and backpropagates through it, saving the
+// changes to the weights of each Network. It returns the outputs at each time step.
+func (b *BiNetwork) train(inputs, targets [][]float64) [][]float64 {
+	for _, net := range b.networks() {
+		net.training = true
+	}
+
+	outs := b.evaluate(inputs)
+
+	inDeltas := make([][]float64, len(inputs))
+	b.head.backpropSequence(len(inputs), func(t int) {
+		b.head.getDeltas(targets[t])
+		inDeltas[t] = b.head.getInputDeltas()
+	})
+
+	split := b.forwards.OutputSize()
+	b.forwards.backpropSequence(len(inputs), func(t int) {
+		b.forwards.backpropagate(inDeltas[t][:split])
+	})
+
+	// the backwards Network was given the sequence in reverse, so its time steps are reversed as
+	// well
+	last := len(inputs) - 1
+	b.backwards.backpropSequence(len(inputs), func(t int) {
+		b.backwards.backpropagate(inDeltas[last-t][split:])
+	})
+
+	for _, net := range b.networks() {
+		net.training = false
+	}
+
+	return outs
+}
+
+// getSequence gets each Datum from 'data', starting at the given iteration, until the end of the
+// sequence. It returns the inputs and targets at each time step.
+func (b *BiNetwork) getSequence(data Sequential, iter int, fromTest bool) (inputs, targets [][]float64, err error) {
+	for ; ; iter++ {
+		d, err := data.Get(iter)
+		if err != nil {
+			return nil, nil, GetDataError{TrainContext{iter, fromTest}, err}
+		} else if !b.fits(d) {
+			return nil, nil, BiDoesNotFitError{TrainContext{iter, fromTest}, b, d}
+		}
+
+		inputs = append(inputs, d.Inputs)
+		targets = append(targets, d.Outputs)
+
+		if data.SetEnded(iter) {
+			return inputs, targets, nil
+		}
+	}
+}
+
+// Train trains the BiNetwork following the conditions laid out in the arguments provided, in the
+// same way as (*Network).Train(). The training (and testing) data must be Sequential, so that the
+// end of each sequence is known.
+//
+// Because each sequence is buffered until it has ended, SendStatus, ShouldTest and RunCondition
+// only take effect between sequences. The iteration of each of the Networks is incremented with
+// every Datum, so that their HyperParameters change during training.
+//
+// Train has the same error conditions as (*Network).Train(), except that (2) and (3) apply
+// regardless of delay, and (6) returns type BiDoesNotFitError.
+func (b *BiNetwork) Train(args TrainArgs) error {
+	// handle error cases and set defaults
+	var trainSeq Sequential
+	{
+		if args.Update == nil {
+			args.Update = func(r Result) {}
+		}
+
+		if args.TrainData == nil {
+			return NilArgError{"TrainData"}
+		}
+
+		var ok bool
+		if trainSeq, ok = args.TrainData.(Sequential); !ok {
+			return ErrTrainNotSequential
+		}
+
+		if args.TestData == nil {
+			if args.ShouldTest != nil {
+				return ErrShouldTestButNil
+			} else {
+				args.ShouldTest = func(i int) bool { return false }
+			}
+		} else if _, ok = args.TestData.(Sequential); !ok {
+			return ErrTestNotSequential
+		}
+
+		if args.SendStatus == nil {
+			args.SendStatus = func(i int) bool { return false }
+		}
+
+		if args.RunCondition == nil {
+			return NilArgError{"RunCondition"}
+		}
+
+		if args.IsCorrect == nil {
+			args.IsCorrect = func(a, b []float64) bool { return false }
+		}
+	}
+
+	// each Observer is only included once, even if it is shared between Networks
+	var observers []Observer
+	for _, net := range b.networks() {
+		net.iter = 0
+
+		for _, o := range net.observers() {
+			observers = addObserver(observers, o)
+		}
+	}
+
+	update := func(r Result) {
+		for _, o := range observers {
+			o.Observe(r)
+		}
+
+		args.Update(r)
+	}
+
+	var statusCost, statusCorrect float64
+	var statusSize int
+	var sendStatus bool
+
+	var iter int
+	for {
+		if sendStatus && statusSize != 0 {
+			update(Result{
+				Iteration: iter,
+				Cost:      statusCost / float64(statusSize),
+				Correct:   statusCorrect / float64(statusSize),
+				IsTest:    false,
+			})
+
+			statusCost, statusCorrect = 0, 0
+			statusSize = 0
+			sendStatus = false
+		}
+
+		if args.ShouldTest(iter) {
+			cost, correct, err := b.Test(args.TestData, args.IsCorrect)
+			if err != nil {
+				return err
+			}
+
+			update(Result{
+				Iteration: iter,
+				Cost:      cost,
+				Correct:   correct,
+				IsTest:    true,
+			})
+		}
+
+		if !args.RunCondition(iter) {
+			break
+		}
+
+		inputs, targets, err := b.getSequence(trainSeq, iter, false)
+		if err != nil {
+			return err
+		}
+
+		// a batch may end partway through the sequence; if it does, the changes are added at the
+		// end of the sequence, in the same way as they are by (*Network).Train()
+		var endBatch bool
+		for i := range inputs {
+			endBatch = trainSeq.BatchEnded(iter+i) || endBatch
+		}
+
+		outs := b.train(inputs, targets)
+		for t := range outs {
+			if len(targets[t]) == 0 {
+				continue
+			}
+
+			statusCost += b.head.cf.Cost(outs[t], targets[t])
+			if args.IsCorrect(outs[t], targets[t]) {
+				statusCorrect += 1.0
+			}
+			statusSize++
+		}
+
+		for _, net := range b.networks() {
+			if endBatch {
+				net.AddWeights()
+			}
+
+			net.endSequence()
+			net.ClearDelays()
+
+			if endBatch {
+				if err := net.endBatch(); err != nil {
+					return err
+				}
+			}
+		}
+
+		for range inputs {
+			sendStatus = sendStatus || (args.SendStatus(iter) && iter != 0)
+
+			iter++
+			for _, net := range b.networks() {
+				net.iter++
+				net.longIter++
+			}
+		}
+	}
+
+	// finish up before returning
+	var batchErr error
+	for _, net := range b.networks() {
+		net.AddWeights()
+
+		if err := net.endBatch(); err != nil && batchErr == nil {
+			batchErr = err
+		}
+	}
+
+	return batchErr
+}
+
+// Test tests the BiNetwork on the supplied data, in the same way as (*Network).Test(). 'data' must
+// be Sequential, or Test will return ErrTestNotSequential. The other error conditions are the same
+// as for (*Network).Test(), except that data that do not fit return type BiDoesNotFitError.
+func (b *BiNetwork) Test(data DataSupplier, isCorrect func([]float64, []float64) bool) (float64, float64, error) {
+	dataSeq, ok := data.(Sequential)
+	if !ok {
+		return 0, 0, ErrTestNotSequential
+	}
+
+	var avgCost, avgCorrect float64
+	var testSize int
+
+	for iter := 0; ; {
+		inputs, targets, err := b.getSequence(dataSeq, iter, true)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		outs := b.evaluate(inputs)
+		b.clear()
+
+		for t := range outs {
+			if len(targets[t]) == 0 {
+				continue
+			}
+
+			avgCost += b.head.cf.Cost(outs[t], targets[t])
+			if isCorrect(outs[t], targets[t]) {
+				avgCorrect += 1
+			}
+			testSize++
+		}
+
+		iter += len(inputs)
+		if data.DoneTesting(iter - 1) {
+			break
+		}
+	}
+
+	if testSize != 0 {
+		avgCost /= float64(testSize)
+		avgCorrect /= float64(testSize)
+	}
+
+	return avgCost, avgCorrect, nil
+}
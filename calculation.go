@@ -3,6 +3,7 @@ package badstudent
 import (
 	"github.com/sharnoff/badstudent/utils"
 	"fmt"
+	"math"
 )
 
 type status int8
@@ -22,8 +23,8 @@ func (net *Network) resetCompletion() {
 	}
 }
 
-// ClearDelays clears all delay-related functions for all nodes in the Network. It
-// essentially flushes a recurrent-type Network.
+// ClearDelays clears all delay-related functions for all nodes in the Network, and resets the state
+// of any Stateful Operators. It essentially flushes a recurrent-type Network.
 func (net *Network) ClearDelays() {
 	for _, n := range net.nodesByID {
 		for i := 0; i < cap(n.delay); i++ {
@@ -35,6 +36,10 @@ func (net *Network) ClearDelays() {
 		}
 
 		n.storedValues = nil
+
+		if s, ok := n.op.(Stateful); ok {
+			s.ResetState(n)
+		}
 	}
 }
 
@@ -68,7 +73,7 @@ func (err InstantCycleError) Error() string {
 // Checks:
 // * all nodes affect outputs
 // * there are no loops with zero delay
-// Determines:
+// Determines (through setDeltaNeeds):
 // * each node's need for calculating input deltas
 //
 // returns DoesNotAffectOutputsError or InstantCycleError
@@ -167,58 +172,61 @@ func (net *Network) checkGraph() error {
 		}
 	}
 
-	// determine each node's need for calculating input deltas
-	{
-		// if deltas should not be calculated, it will be indicated by the
-		// deltas of the Node having length 0
+	net.setDeltaNeeds()
 
-		var matter func(*Node, bool)
-		matter = func(n *Node, dm bool) { // 'dm' is short for 'deltas matter'
-			if n.completed && (len(n.deltas) != 0 || !dm) {
-				return
-			}
+	return nil
+}
 
-			if n.IsInput() {
-				dm = false
-			} else {
-				dm = dm || n.adj != nil // if n is adjustable, deltas matter
-			}
+// setDeltaNeeds determines each Node's need for calculating deltas and input deltas. The input
+// Nodes only have deltas if they are needed by another Network (see Bidirectional).
+func (net *Network) setDeltaNeeds() {
+	// if deltas should not be calculated, it will be indicated by the
+	// deltas of the Node having length 0
 
-			if dm {
-				n.deltas = make([]float64, n.Size())
-			}
-			n.completed = true
+	var matter func(*Node, bool)
+	matter = func(n *Node, dm bool) { // 'dm' is short for 'deltas matter'
+		if n.completed && (len(n.deltas) != 0 || !dm) {
+			return
+		}
 
-			for _, out := range n.outputs.nodes {
-				matter(out, dm)
-			}
+		if n.IsInput() {
+			dm = net.inputDeltas
+		} else {
+			dm = dm || n.adj != nil // if n is adjustable, deltas matter
+		}
 
-			return
+		if dm {
+			n.deltas = make([]float64, n.Size())
 		}
+		n.completed = true
 
-		for _, in := range net.inputs.nodes {
-			matter(in, false)
+		for _, out := range n.outputs.nodes {
+			matter(out, dm)
 		}
 
-		for _, n := range net.nodesByID {
-			n.calcInDeltas = false
+		return
+	}
 
-			if len(n.deltas) == 0 {
-				continue
-			}
+	for _, in := range net.inputs.nodes {
+		matter(in, false)
+	}
 
-			for _, in := range n.inputs.nodes {
-				if len(in.deltas) != 0 {
-					n.calcInDeltas = true
-					break
-				}
-			}
+	for _, n := range net.nodesByID {
+		n.calcInDeltas = false
+
+		if len(n.deltas) == 0 || n.IsInput() {
+			continue
 		}
 
-		net.resetCompletion()
+		for _, in := range n.inputs.nodes {
+			if len(in.deltas) != 0 {
+				n.calcInDeltas = true
+				break
+			}
+		}
 	}
 
-	return nil
+	net.resetCompletion()
 }
 
 // setValues does not check length
@@ -288,36 +296,49 @@ func (n *Node) evaluate() {
 		return
 	}
 
+	// Nodes with delay keep the values from earlier time steps for all of the current one, so that
+	// every Node that uses them sees the same values. Their next values are calculated separately,
+	// by evaluateNext, once everything else has been evaluated.
 	if n.HasDelay() {
 		if !n.host.isGettingDeltas() {
 			// forward calculation, set from delay
 			n.setValues(<-n.delay)
+			n.storeValues()
 		} else {
 			// backprop, set from stored
 			n.setFromStored()
 		}
+
 		n.completed = true
+		return
 	}
 
 	for _, in := range n.inputs.nodes {
 		in.evaluate()
 	}
 
-	if n.HasDelay() {
-		n.values.Values = make([]float64, n.values.Size())
-	}
-
 	n.calculateValues()
+	n.completed = true
+}
 
-	if n.HasDelay() && !n.host.isGettingDeltas() {
-		n.delay <- n.values.Values
-		n.storedValues = append(n.storedValues, n.values.Values)
+// evaluateNext calculates the values that a Node with delay will have after its delay, from the
+// values of its inputs at the current time step. The values of the Node itself are not changed.
+func (n *Node) evaluateNext() {
+	for _, in := range n.inputs.nodes {
+		in.evaluate()
 	}
 
-	// if we're backpropagating, don't worry about setting values; they were
-	// set earlier
+	current := n.values.Values
+	n.values.Values = make([]float64, n.values.Size())
+	n.calculateValues()
 
-	n.completed = true
+	n.nextValues = n.values.Values
+	n.values.Values = current
+
+	// if we're backpropagating, the next values were already given to the delay
+	if !n.host.isGettingDeltas() {
+		n.delay <- n.nextValues
+	}
 }
 
 // evaluate updates the values of every Node to reflect the inputs. If the Network is not recurrent
@@ -335,6 +356,21 @@ func (net *Network) evaluate() error {
 		out.evaluate()
 	}
 
+	// every Node with delay must take its values for this time step before any of them are given
+	// their next values. Otherwise, a Node with delay that feeds into another could be given new
+	// values before the old ones were read, which would block on the full delay.
+	for _, n := range net.nodesByID {
+		if n.HasDelay() {
+			n.evaluate()
+		}
+	}
+
+	for _, n := range net.nodesByID {
+		if n.HasDelay() {
+			n.evaluateNext()
+		}
+	}
+
 	net.resetCompletion()
 	net.stat = evaluated
 
@@ -391,8 +427,12 @@ func (n *Node) inputDeltas() {
 	// they [the root node] calculate their own.
 
 	if n.HasDelay() {
+		// the deltas of Nodes with delay are for their next values, so those are used instead
 		if n.calcInDeltas {
+			current := n.values.Values
+			n.values.Values = n.nextValues
 			n.calculateInputDeltas()
+			n.values.Values = current
 		}
 
 		n.completed = true
@@ -402,6 +442,12 @@ func (n *Node) inputDeltas() {
 		o.inputDeltas()
 	}
 
+	// if the Node is part of a loop, its input deltas may have already been calculated while
+	// recursing through its outputs
+	if n.completed {
+		return
+	}
+
 	if !n.HasDelay() && n.calcInDeltas {
 		n.calculateInputDeltas()
 	}
@@ -411,6 +457,23 @@ func (n *Node) inputDeltas() {
 
 // assumes len(targets) == net.OutputSize(), net.stat >= evaluated
 func (net *Network) getDeltas(targets []float64) {
+	// we check if len(targets) is zero because recurrent models can exempt certain outputs from
+	// having significance by indicating providing no targets
+	var ds []float64
+	if len(targets) != 0 {
+		// Indicating 'false' for duplicating opens the possibility of cost
+		// functions to corrupt data. This issue is not significant.
+		ds = net.cf.Derivs(net.outputs.getValues(false), targets)
+	}
+
+	net.backpropagate(ds)
+}
+
+// backpropagate calculates the deltas of every Node from the given deltas of the outputs, which
+// may be nil if the outputs have no significance.
+//
+// assumes len(outDeltas) == net.OutputSize() or 0, net.stat >= evaluated
+func (net *Network) backpropagate(outDeltas []float64) {
 	// reset deltas. For nodes without a need to calculate deltas, this will keep len(deltas) = 0.
 	for _, n := range net.nodesByID {
 		if n.HasDelay() {
@@ -422,15 +485,8 @@ func (net *Network) getDeltas(targets []float64) {
 	}
 
 	// add to output deltas
-	if len(targets) != 0 {
-		// we check if len(targets) is zero because recurrent models can exempt
-		// certain outputs from having significance by indicating providing no
-		// targets
-
-		// Indicating 'false' for duplicating opens the possibility of cost
-		// functions to corrupt data. This issue is not significant.
-		ds := net.cf.Derivs(net.outputs.getValues(false), targets)
-		net.outputs.addDeltas(ds)
+	if len(outDeltas) != 0 {
+		net.outputs.addDeltas(outDeltas)
 	}
 
 	// recurse through network
@@ -450,6 +506,30 @@ func (net *Network) getDeltas(targets []float64) {
 	return
 }
 
+// getInputDeltas returns the deltas of the inputs to the Network, which are only calculated if
+// net.inputDeltas is true
+func (net *Network) getInputDeltas() []float64 {
+	ds := make([]float64, 0, net.InputSize())
+	for _, in := range net.inputs.nodes {
+		ds = append(ds, in.deltas...)
+	}
+
+	return ds
+}
+
+// backpropSequence backpropagates through each of the given number of time steps of the most
+// recent sequence in reverse order, saving the changes to the weights. The Network must be
+// recurrent, so that each time step is replayed instead of being evaluated again. getDeltas is
+// called with the index of each time step, once the Network has been evaluated, and must calculate
+// the deltas of the Network.
+func (net *Network) backpropSequence(steps int, getDeltas func(int)) {
+	for t := steps - 1; t >= 0; t-- {
+		net.evaluate()
+		getDeltas(t)
+		net.adjust(true)
+	}
+}
+
 // Assumptions:
 //	* net.stat >= finalized
 //	* len(targets[n]) == net.OutputSize(), for all n in range len(targets)
@@ -470,8 +550,16 @@ func (net *Network) adjustRecurrent(targets [][]float64, saveChanges bool) {
 
 // does not use completion, as it iterates through every Node directly.
 func (net *Network) adjust(saveChanges bool) {
+	// the global norm must be calculated across all Nodes before any of them are adjusted
+	stored, scale := net.gradScale()
+
 	for _, n := range net.nodesByID {
-		n.adjust(saveChanges)
+		var grads Adjustable
+		if stored != nil {
+			grads = stored[n.id]
+		}
+
+		n.adjust(saveChanges, grads, scale)
 	}
 
 	if saveChanges {
@@ -481,7 +569,92 @@ func (net *Network) adjust(saveChanges bool) {
 	return
 }
 
-func (n *Node) adjust(saveChanges bool) {
+// gradScale returns the factor that all gradients must be multiplied by in order to keep the
+// global L2 norm of the gradients across all Nodes at or below the Network's clipping threshold.
+// If there is no threshold, or the norm is already small enough, the factor is 1.
+//
+// Because every gradient must be calculated to find the norm, gradScale also returns them, so that
+// they are not calculated again by the Optimizers. They are given as an Adjustable for each Node,
+// indexed by ID, which is nil if the Node is not Adjustable. If there is no threshold, gradScale
+// returns nil instead.
+//
+// The norm is of the gradients of the current sample; when training in batches, each sample is
+// clipped separately before its changes are added to those of the batch.
+func (net *Network) gradScale() ([]Adjustable, float64) {
+	if net.clipNorm == 0 {
+		return nil, 1
+	}
+
+	stored := make([]Adjustable, len(net.nodesByID))
+
+	var sum float64
+	for _, n := range net.nodesByID {
+		if n.adj == nil {
+			continue
+		}
+
+		adj := n.penalized()
+		grads := storedAdj{Adjustable: adj}
+
+		if idx, ok := n.Touched(); ok {
+			grads.sparse = make(map[int]float64, len(idx))
+			for _, i := range idx {
+				g := adj.Grad(n, i)
+				grads.sparse[i] = g
+				sum += g * g
+			}
+		} else {
+			grads.dense = make([]float64, len(n.adj.Weights()))
+			for i := range grads.dense {
+				g := adj.Grad(n, i)
+				grads.dense[i] = g
+				sum += g * g
+			}
+		}
+
+		stored[n.id] = grads
+	}
+
+	if norm := math.Sqrt(sum); norm > net.clipNorm {
+		return stored, net.clipNorm / norm
+	}
+
+	return stored, 1
+}
+
+// penalized returns the Node's Adjustable, wrapped to add its Penalty if it has one
+func (n *Node) penalized() Adjustable {
+	if n.pen != nil {
+		return penAdj{n.adj}
+	}
+
+	return n.adj
+}
+
+// gradAdj returns the Adjustable that should be given to the Node's Optimizer: the Node's own,
+// wrapped to add its Penalty and to clip its gradients, if either is necessary. If the gradients
+// have already been calculated by (*Network).gradScale(), they are given by 'stored', which is
+// used in place of the Node's own (with its Penalty). 'scale' is the factor given by gradScale; it
+// is applied before value clipping.
+func (n *Node) gradAdj(stored Adjustable, scale float64) Adjustable {
+	adj := stored
+	if adj == nil {
+		adj = n.penalized()
+	}
+
+	limit := n.clipValue
+	if limit == 0 {
+		limit = n.host.clipValue
+	}
+
+	if scale != 1 || limit != 0 {
+		adj = clipAdj{adj, scale, limit}
+	}
+
+	return adj
+}
+
+func (n *Node) adjust(saveChanges bool, stored Adjustable, scale float64) {
 	if n.adj == nil {
 		return
 	}
@@ -494,14 +667,51 @@ func (n *Node) adjust(saveChanges bool) {
 		w = n.delayedWeights
 	}
 
-	var adj Adjustable
-	if n.pen != nil {
-		adj = penAdj{n.adj}
-	} else {
-		adj = n.adj
+	// without batching, the changes are made to the weights directly, so they must be decayed
+	// before the Optimizer changes them, in the same way as they are by addWeights
+	if !saveChanges {
+		n.decayWeights(w)
+	}
+
+	n.opt.Run(n, n.gradAdj(stored, scale), w)
+
+	if !saveChanges {
+		n.endBatch()
+	}
+}
+
+// decayWeights adds the decoupled weight decay, given by the HyperParameter "weight-decay", to the
+// provided changes. If the Node does not have that HyperParameter, decayWeights does nothing.
+//
+// Weight decay is applied once per batch, when the changes are added to the weights, so that it
+// does not depend on the batch size. It is always calculated from the weights before the changes
+// given by the Optimizer.
+func (n *Node) decayWeights(changes []float64) {
+	hp, ok := n.hp(WeightDecay)
+	if !ok {
+		return
 	}
 
-	n.opt.Run(n, adj, w)
+	λ := hp.Value(n.host.longIter)
+	if λ == 0 {
+		return
+	}
+
+	ws := n.adj.Weights()
+
+	f := func(i int) {
+		changes[i] -= λ * ws[i]
+	}
+
+	utils.MultiThread(0, len(ws), f, opsPerThread, threadsPerCPU)
+}
+
+// endBatch notifies the Node's Optimizer that changes have been added to its weights, if the
+// Optimizer requires it.
+func (n *Node) endBatch() {
+	if b, ok := n.opt.(BatchOptimizer); ok {
+		b.EndBatch(n)
+	}
 }
 
 // penAdj is a wrapper for the usual Adjustable found in Nodes, to allow for the same types of
@@ -515,7 +725,43 @@ func (p penAdj) Grad(n *Node, index int) float64 {
 }
 
 func (p penAdj) Weights() []float64 {
-	return p.Weights()
+	return p.Adjustable.Weights()
+}
+
+// clipAdj is a wrapper similar to penAdj, which scales the gradients given by the Adjustable it
+// wraps and then clips them to the range [-limit, limit]. A limit of zero indicates that there is
+// no value clipping.
+type clipAdj struct {
+	Adjustable
+
+	scale, limit float64
+}
+
+func (c clipAdj) Grad(n *Node, index int) float64 {
+	g := c.Adjustable.Grad(n, index) * c.scale
+	if c.limit != 0 {
+		g = math.Max(-c.limit, math.Min(c.limit, g))
+	}
+
+	return g
+}
+
+// storedAdj is a wrapper similar to penAdj, which gives gradients that have already been
+// calculated instead of calculating them again. The gradients are stored for every weight, or only
+// for those that were touched if the Operator is Sparse.
+type storedAdj struct {
+	Adjustable
+
+	dense  []float64
+	sparse map[int]float64
+}
+
+func (s storedAdj) Grad(n *Node, index int) float64 {
+	if s.dense != nil {
+		return s.dense[index]
+	}
+
+	return s.sparse[index]
 }
 
 func (n *Node) addWeights() {
@@ -528,6 +774,8 @@ func (n *Node) addWeights() {
 		n.adj.Weights()[i] += n.delayedWeights[i]
 	} */
 
+	n.decayWeights(n.delayedWeights)
+
 	ws := n.adj.Weights()
 
 	f := func(i int) {
@@ -536,6 +784,8 @@ func (n *Node) addWeights() {
 
 	utils.MultiThread(0, len(ws), f, opsPerThread, threadsPerCPU)
 	n.delayedWeights = make([]float64, len(ws))
+
+	n.endBatch()
 }
 
 // Updates the weights in the network with any previously saved changes.
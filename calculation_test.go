@@ -0,0 +1,53 @@
+package badstudent_test
+
+import (
+	"testing"
+	"time"
+
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/costfuncs"
+	"github.com/sharnoff/badstudent/operators"
+)
+
+// Delayed Nodes that feed into other delayed Nodes must all be read from before any of them are
+// given their next values, or evaluation blocks on a full delay.
+func TestChainedDelays(t *testing.T) {
+	net := new(bs.Network)
+	in := net.AddInput([]int{1})
+	first := net.Add(operators.Identity(), in).SetDelay(1)
+	second := net.Add(operators.Identity(), first).SetDelay(1)
+	out := net.Add(operators.Identity(), second)
+
+	if err := net.Finalize(costfuncs.MSE(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan []float64)
+	go func() {
+		var outs []float64
+		for i := 1; i <= 4; i++ {
+			o, err := net.GetOutputs([]float64{float64(i)})
+			if err != nil {
+				t.Error(err)
+				break
+			}
+
+			outs = append(outs, o[0])
+		}
+
+		done <- outs
+	}()
+
+	select {
+	case outs := <-done:
+		// each input reaches the output two time steps later
+		expected := []float64{0, 0, 1, 2}
+		for i := range expected {
+			if outs[i] != expected[i] {
+				t.Fatalf("Unexpected outputs: %v (expected %v)", outs, expected)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Evaluation did not finish; delayed Nodes are blocked")
+	}
+}
@@ -42,6 +42,10 @@ var (
 	ErrNotPlaceholder   = Error{"Cannot replace non-placeholder Node"}
 	ErrDelayInput       = Error{"Cannot set delay of an input Node"}
 
+	ErrInvalidClip = Error{"Clipping threshold must be > 0"}
+
+	ErrNestedStorable = Error{"Storable HyperParameters cannot be nested inside others"}
+
 	ErrNoHP          = Error{"No HyperParameter by given name"}
 	ErrNoInputValues = Error{"Node is an input; does not have input values."}
 
@@ -55,6 +59,8 @@ var (
 	ErrNoData             = Error{"Given dataset has no data (len=0)"}
 	ErrSmallBatchSize     = Error{"Given batch size is less than 1"}
 	ErrSmallSetSize       = Error{"Given set size is less than 1"}
+
+	ErrSharedNetwork = Error{"The same Network cannot be used more than once in a BiNetwork"}
 )
 
 // NilArgError documents errors resulting from certain arguments provided to a function being nil.
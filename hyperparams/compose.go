@@ -0,0 +1,251 @@
+// compose.go contains HyperParameters that are built out of others:
+// * Warmup
+// * Sum
+// * Product
+// * Clamp
+// * Offset
+//
+// Any HyperParameter can be given to these, including others from this file, so long as it is not
+// Storable. They are saved and loaded through badstudent.NestedHP. All of them are
+// badstudent.Observers, and pass the Results they are given on to any inner HyperParameters that
+// are Observers.
+package hyperparams
+
+import (
+	bs "github.com/sharnoff/badstudent"
+	"math"
+)
+
+func nest(hps []bs.HyperParameter) []bs.NestedHP {
+	ns := make([]bs.NestedHP, len(hps))
+	for i := range hps {
+		ns[i] = bs.NestedHP{HyperParameter: hps[i]}
+	}
+
+	return ns
+}
+
+// observe passes the Result on to any of the given HyperParameters that are Observers
+func observe(r bs.Result, hps ...bs.NestedHP) {
+	for _, h := range hps {
+		if o, ok := h.HyperParameter.(bs.Observer); ok {
+			o.Observe(r)
+		}
+	}
+}
+
+// ****************************************
+// Warmup
+// ****************************************
+
+type warmup struct {
+	Inner bs.NestedHP
+	Iters int
+
+	// whether or not the warmup is exponential instead of linear
+	Exp bool
+}
+
+// Warmup returns a HyperParameter that ramps up linearly from zero to the values given by inner
+// over the first 'iters' iterations. After that, it gives the same values as inner. The iteration
+// given to inner is not changed; to start inner once the warmup has finished, use Offset.
+//
+// Warmup will panic if iters < 1.
+func Warmup(inner bs.HyperParameter, iters int) *warmup {
+	if iters < 1 {
+		panic("given number of iterations is < 1")
+	}
+
+	return &warmup{Inner: bs.NestedHP{HyperParameter: inner}, Iters: iters}
+}
+
+// Exponential changes the warmup to ramp up exponentially, multiplying inner by
+// 1 - e^(-iter / iters). Instead of reaching inner at 'iters', it approaches it asymptotically,
+// with 'iters' as the time constant.
+func (w *warmup) Exponential() *warmup {
+	w.Exp = true
+	return w
+}
+
+func (w *warmup) TypeString() string {
+	return "warmup"
+}
+
+func (w *warmup) Value(iter int) float64 {
+	t := math.Max(0, float64(iter)) / float64(w.Iters)
+
+	var factor float64
+	if w.Exp {
+		factor = 1 - math.Exp(-t)
+	} else {
+		factor = math.Min(1, t)
+	}
+
+	return factor * w.Inner.Value(iter)
+}
+
+func (w *warmup) Observe(r bs.Result) {
+	observe(r, w.Inner)
+}
+
+func (w *warmup) Get() interface{} {
+	return *w
+}
+
+func (w *warmup) Blank() interface{} {
+	return w
+}
+
+// ****************************************
+// Sum
+// ****************************************
+
+type sum struct {
+	Terms []bs.NestedHP
+}
+
+// Sum returns a HyperParameter that gives the sum of the values of all of the given
+// HyperParameters.
+func Sum(hps ...bs.HyperParameter) *sum {
+	return &sum{nest(hps)}
+}
+
+func (s *sum) TypeString() string {
+	return "sum"
+}
+
+func (s *sum) Value(iter int) float64 {
+	var v float64
+	for _, t := range s.Terms {
+		v += t.Value(iter)
+	}
+
+	return v
+}
+
+func (s *sum) Observe(r bs.Result) {
+	observe(r, s.Terms...)
+}
+
+func (s *sum) Get() interface{} {
+	return *s
+}
+
+func (s *sum) Blank() interface{} {
+	return s
+}
+
+// ****************************************
+// Product
+// ****************************************
+
+type product struct {
+	Factors []bs.NestedHP
+}
+
+// Product returns a HyperParameter that gives the product of the values of all of the given
+// HyperParameters. For example, the following gives a linear warmup into cosine annealing:
+//
+//	Product(Warmup(Constant(1), 1000), Offset(Cosine(0.1, 0, 9000), 1000))
+func Product(hps ...bs.HyperParameter) *product {
+	return &product{nest(hps)}
+}
+
+func (p *product) TypeString() string {
+	return "product"
+}
+
+func (p *product) Value(iter int) float64 {
+	v := 1.0
+	for _, f := range p.Factors {
+		v *= f.Value(iter)
+	}
+
+	return v
+}
+
+func (p *product) Observe(r bs.Result) {
+	observe(r, p.Factors...)
+}
+
+func (p *product) Get() interface{} {
+	return *p
+}
+
+func (p *product) Blank() interface{} {
+	return p
+}
+
+// ****************************************
+// Clamp
+// ****************************************
+
+type clamp struct {
+	Inner    bs.NestedHP
+	Min, Max float64
+}
+
+// Clamp returns a HyperParameter that gives the values of inner, limited to the range [min, max].
+func Clamp(inner bs.HyperParameter, min, max float64) *clamp {
+	return &clamp{bs.NestedHP{HyperParameter: inner}, min, max}
+}
+
+func (c *clamp) TypeString() string {
+	return "clamp"
+}
+
+func (c *clamp) Value(iter int) float64 {
+	return math.Max(c.Min, math.Min(c.Max, c.Inner.Value(iter)))
+}
+
+func (c *clamp) Observe(r bs.Result) {
+	observe(r, c.Inner)
+}
+
+func (c *clamp) Get() interface{} {
+	return *c
+}
+
+func (c *clamp) Blank() interface{} {
+	return c
+}
+
+// ****************************************
+// Offset
+// ****************************************
+
+type offset struct {
+	Inner bs.NestedHP
+	Iters int
+}
+
+// Offset returns a HyperParameter that delays inner by the given number of iterations, so that
+// inner is given iteration 0 at 'iters'. Before then, it gives the value of inner at iteration 0.
+func Offset(inner bs.HyperParameter, iters int) *offset {
+	return &offset{bs.NestedHP{HyperParameter: inner}, iters}
+}
+
+func (o *offset) TypeString() string {
+	return "offset"
+}
+
+func (o *offset) Value(iter int) float64 {
+	iter -= o.Iters
+	if iter < 0 {
+		iter = 0
+	}
+
+	return o.Inner.Value(iter)
+}
+
+func (o *offset) Observe(r bs.Result) {
+	observe(r, o.Inner)
+}
+
+func (o *offset) Get() interface{} {
+	return *o
+}
+
+func (o *offset) Blank() interface{} {
+	return o
+}
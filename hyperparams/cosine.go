@@ -0,0 +1,97 @@
+package hyperparams
+
+import (
+	"math"
+)
+
+type cosine struct {
+	Max, Min float64
+
+	// the length of the first cycle
+	Period int
+
+	// whether or not the value restarts at Max at the end of each cycle
+	Restart bool
+
+	// the factor that the length of each cycle is multiplied by after it ends. Only used if Restart
+	// is true.
+	Mult float64
+}
+
+// Cosine returns a HyperParameter that decreases from max to min over the given number of
+// iterations, following half of a cosine curve. After the period has ended, it stays at min, unless
+// warm restarts have been added with Restarts.
+//
+// Cosine will panic if period < 1.
+//
+// For more information, see: https://arxiv.org/abs/1608.03983
+func Cosine(max, min float64, period int) *cosine {
+	if period < 1 {
+		panic("given period is < 1")
+	}
+
+	return &cosine{
+		Max:    max,
+		Min:    min,
+		Period: period,
+		Mult:   1,
+	}
+}
+
+// Restarts adds warm restarts to the cosine annealing (i.e. SGDR), so that the value jumps back up
+// to max at the end of each cycle. The length of each cycle is multiplied by mult at each restart;
+// mult = 1 gives cycles of equal length.
+//
+// Restarts will panic if mult < 1.
+func (c *cosine) Restarts(mult float64) *cosine {
+	if mult < 1 {
+		panic("given period multiplier is < 1")
+	}
+
+	c.Restart = true
+	c.Mult = mult
+	return c
+}
+
+func (c *cosine) TypeString() string {
+	return "cosine"
+}
+
+func (c *cosine) Value(iter int) float64 {
+	t := math.Max(0, float64(iter))
+	p := float64(c.Period)
+
+	if !c.Restart {
+		if t >= p {
+			return c.Min
+		}
+	} else if c.Mult == 1 {
+		t = math.Mod(t, p)
+	} else {
+		// The n'th cycle starts at: Period * (Mult^n - 1) / (Mult - 1)
+		n := math.Floor(math.Log(1+t*(c.Mult-1)/p) / math.Log(c.Mult))
+		start := p * (math.Pow(c.Mult, n) - 1) / (c.Mult - 1)
+
+		t -= start
+		p *= math.Pow(c.Mult, n)
+
+		// guard against floating-point error at the boundaries of cycles
+		if t >= p {
+			t -= p
+			p *= c.Mult
+		} else if t < 0 {
+			p /= c.Mult
+			t += p
+		}
+	}
+
+	return c.Min + 0.5*(c.Max-c.Min)*(1+math.Cos(math.Pi*t/p))
+}
+
+func (c *cosine) Get() interface{} {
+	return *c
+}
+
+func (c *cosine) Blank() interface{} {
+	return c
+}
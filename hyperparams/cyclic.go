@@ -0,0 +1,239 @@
+// cyclic.go contains HyperParameters that are intended to move the learning rate (and momentum)
+// both up and down over the course of training:
+// * Cyclical
+// * OneCycle
+//
+// Both are determined entirely by the iteration they are given, so a Network that is saved and
+// loaded partway through a cycle will continue from the same point.
+package hyperparams
+
+import (
+	"math"
+)
+
+// ****************************************
+// Cyclical
+// ****************************************
+
+// the modes of cyclical
+const (
+	triangular  string = "triangular"
+	triangular2 string = "triangular2"
+	expRange    string = "exp-range"
+)
+
+type cyclical struct {
+	Min, Max float64
+
+	// the number of iterations in half of a cycle
+	StepSize int
+
+	// one of "triangular", "triangular2", or "exp-range"
+	Mode string
+
+	// the factor that the amplitude is multiplied by at each iteration. Only used with "exp-range"
+	Gamma float64
+}
+
+// Cyclical returns a HyperParameter that moves linearly back and forth between min and max,
+// starting at min and taking 'stepSize' iterations to go from one to the other. This is the
+// "triangular" policy. The amplitude of the cycles can be made to decrease with Triangular2 or
+// ExpRange.
+//
+// Cyclical will panic if stepSize < 1.
+//
+// For more information, see: https://arxiv.org/abs/1506.01186
+func Cyclical(min, max float64, stepSize int) *cyclical {
+	if stepSize < 1 {
+		panic("given step size is < 1")
+	}
+
+	return &cyclical{
+		Min:      min,
+		Max:      max,
+		StepSize: stepSize,
+		Mode:     triangular,
+		Gamma:    1,
+	}
+}
+
+// Triangular2 changes the policy so that the difference between min and max is halved at the end
+// of each cycle.
+func (c *cyclical) Triangular2() *cyclical {
+	c.Mode = triangular2
+	return c
+}
+
+// ExpRange changes the policy so that the difference between min and max is multiplied by
+// gamma^iter. Gamma is usually slightly less than 1 (e.g. 0.99994).
+//
+// ExpRange will panic if gamma <= 0.
+func (c *cyclical) ExpRange(gamma float64) *cyclical {
+	if gamma <= 0 {
+		panic("given gamma is <= 0")
+	}
+
+	c.Mode = expRange
+	c.Gamma = gamma
+	return c
+}
+
+func (c *cyclical) TypeString() string {
+	return "cyclical"
+}
+
+func (c *cyclical) Value(iter int) float64 {
+	t := math.Max(0, float64(iter))
+	s := float64(c.StepSize)
+
+	cycle := math.Floor(1 + t/(2*s))
+	x := math.Abs(t/s - 2*cycle + 1)
+
+	var scale float64
+	switch c.Mode {
+	case triangular2:
+		scale = 1 / math.Pow(2, cycle-1)
+	case expRange:
+		scale = math.Pow(c.Gamma, t)
+	default:
+		scale = 1
+	}
+
+	return c.Min + (c.Max-c.Min)*math.Max(0, 1-x)*scale
+}
+
+func (c *cyclical) Get() interface{} {
+	return *c
+}
+
+func (c *cyclical) Blank() interface{} {
+	return c
+}
+
+// ****************************************
+// OneCycle
+// ****************************************
+
+type oneCycle struct {
+	// the values at the start of the cycle, the peak (for the learning rate; the trough for
+	// momentum), and the end of the cycle
+	Initial, Peak, Final float64
+
+	// the total length of the cycle
+	Iters int
+
+	// the fraction of Iters that is spent moving from Initial to Peak
+	Warm float64
+
+	// whether or not the values change linearly, instead of following a cosine curve
+	Lin bool
+}
+
+// OneCycle returns a HyperParameter for the learning rate that follows the one-cycle policy: over
+// the first 30% of 'iters' iterations, it increases from max/25 to max. Over the remaining
+// iterations, it decreases to max/(25*1e4), where it stays once the cycle has finished. These
+// defaults can be changed with Phases and Divisors, and the curve can be made linear with Linear.
+//
+// The matching schedule for momentum, which moves in the opposite direction to the learning rate,
+// can be given by InverseMomentum.
+//
+// OneCycle will panic if iters < 1.
+//
+// For more information, see: https://arxiv.org/abs/1803.09820
+func OneCycle(max float64, iters int) *oneCycle {
+	if iters < 1 {
+		panic("given number of iterations is < 1")
+	}
+
+	return &oneCycle{
+		Initial: max / 25,
+		Peak:    max,
+		Final:   max / (25 * 1e4),
+		Iters:   iters,
+		Warm:    0.3,
+	}
+}
+
+// Phases sets the fraction of the cycle that is spent increasing the learning rate. The default is
+// 0.3.
+//
+// Phases will panic if warm is not within the range (0, 1).
+func (o *oneCycle) Phases(warm float64) *oneCycle {
+	if warm <= 0 || warm >= 1 {
+		panic("given fraction is not within the range (0, 1)")
+	}
+
+	o.Warm = warm
+	return o
+}
+
+// Divisors sets the initial and final learning rates from the maximum: the cycle starts at
+// max/div and ends at max/(div*finalDiv). The defaults are 25 and 1e4, respectively.
+//
+// Divisors will panic if either value is <= 0.
+func (o *oneCycle) Divisors(div, finalDiv float64) *oneCycle {
+	if div <= 0 || finalDiv <= 0 {
+		panic("given divisor is <= 0")
+	}
+
+	o.Initial = o.Peak / div
+	o.Final = o.Peak / (div * finalDiv)
+	return o
+}
+
+// Linear changes the cycle so that the values change linearly, instead of following half of a
+// cosine curve in each phase.
+func (o *oneCycle) Linear() *oneCycle {
+	o.Lin = true
+	return o
+}
+
+// InverseMomentum returns a new HyperParameter for the momentum that is coupled to the learning
+// rate given by o: it starts at high, decreases to low while the learning rate increases, and
+// returns to high as the learning rate decreases. The timing of the cycle is copied from o, so any
+// changes to it should be made before InverseMomentum is called. Typical values are 0.95 and 0.85.
+func (o *oneCycle) InverseMomentum(high, low float64) *oneCycle {
+	return &oneCycle{
+		Initial: high,
+		Peak:    low,
+		Final:   high,
+		Iters:   o.Iters,
+		Warm:    o.Warm,
+		Lin:     o.Lin,
+	}
+}
+
+func (o *oneCycle) TypeString() string {
+	return "one-cycle"
+}
+
+func (o *oneCycle) Value(iter int) float64 {
+	t := math.Max(0, float64(iter))
+	total := float64(o.Iters)
+	warm := o.Warm * total
+
+	if t >= total {
+		return o.Final
+	} else if t < warm {
+		return o.interpolate(o.Initial, o.Peak, t/warm)
+	}
+
+	return o.interpolate(o.Peak, o.Final, (t-warm)/(total-warm))
+}
+
+// interpolate returns the value at the fraction 'f' of the way from 'start' to 'end'
+func (o *oneCycle) interpolate(start, end, f float64) float64 {
+	if !o.Lin {
+		f = 0.5 * (1 - math.Cos(math.Pi*f))
+	}
+
+	return start + (end-start)*f
+}
+
+func (o *oneCycle) Get() interface{} {
+	return *o
+}
+
+func (o *oneCycle) Blank() interface{} {
+	return o
+}
@@ -0,0 +1,176 @@
+// decay.go contains HyperParameters that decrease continuously from a starting value:
+// * Exponential
+// * InverseTime
+// * Polynomial
+package hyperparams
+
+import (
+	"math"
+)
+
+// ****************************************
+// Exponential
+// ****************************************
+
+type exponential struct {
+	Base, Rate float64
+	Every      int
+
+	// whether or not the value only changes every 'Every' iterations
+	Stairs bool
+}
+
+// Exponential returns a HyperParameter that starts at base and is multiplied by rate every 'every'
+// iterations, giving: base * rate^(iter / every). By default, the decay is applied smoothly; to
+// only change the value at multiples of 'every', use Staircase.
+//
+// Exponential will panic if every < 1.
+func Exponential(base, rate float64, every int) *exponential {
+	if every < 1 {
+		panic("given number of iterations is < 1")
+	}
+
+	return &exponential{Base: base, Rate: rate, Every: every}
+}
+
+// Staircase changes the decay so that the value only changes once every 'every' iterations,
+// instead of continuously.
+func (e *exponential) Staircase() *exponential {
+	e.Stairs = true
+	return e
+}
+
+func (e *exponential) TypeString() string {
+	return "exponential"
+}
+
+func (e *exponential) Value(iter int) float64 {
+	return e.Base * math.Pow(e.Rate, progress(iter, e.Every, e.Stairs))
+}
+
+func (e *exponential) Get() interface{} {
+	return *e
+}
+
+func (e *exponential) Blank() interface{} {
+	return e
+}
+
+// ****************************************
+// InverseTime
+// ****************************************
+
+type inverseTime struct {
+	Base, Rate float64
+	Every      int
+
+	// whether or not the value only changes every 'Every' iterations
+	Stairs bool
+}
+
+// InverseTime returns a HyperParameter that starts at base and decays in proportion to the inverse
+// of the number of iterations, giving: base / (1 + rate * iter / every). As with Exponential, the
+// decay can be restricted to multiples of 'every' with Staircase.
+//
+// InverseTime will panic if every < 1.
+func InverseTime(base, rate float64, every int) *inverseTime {
+	if every < 1 {
+		panic("given number of iterations is < 1")
+	}
+
+	return &inverseTime{Base: base, Rate: rate, Every: every}
+}
+
+// Staircase changes the decay so that the value only changes once every 'every' iterations,
+// instead of continuously.
+func (t *inverseTime) Staircase() *inverseTime {
+	t.Stairs = true
+	return t
+}
+
+func (t *inverseTime) TypeString() string {
+	return "inverse-time"
+}
+
+func (t *inverseTime) Value(iter int) float64 {
+	return t.Base / (1 + t.Rate*progress(iter, t.Every, t.Stairs))
+}
+
+func (t *inverseTime) Get() interface{} {
+	return *t
+}
+
+func (t *inverseTime) Blank() interface{} {
+	return t
+}
+
+// progress returns the number of periods of length 'every' that have passed by the given
+// iteration, rounded down if 'stairs' is true. Negative iterations are treated as zero.
+func progress(iter, every int, stairs bool) float64 {
+	p := math.Max(0, float64(iter)) / float64(every)
+	if stairs {
+		p = math.Floor(p)
+	}
+
+	return p
+}
+
+// ****************************************
+// Polynomial
+// ****************************************
+
+type polynomial struct {
+	Start, End float64
+	Iters      int
+	Power      float64
+
+	// whether or not the decay repeats after it has finished, instead of staying at End
+	Repeat bool
+}
+
+// Polynomial returns a HyperParameter that decreases from start to end over the given number of
+// iterations, following: (start - end) * (1 - iter/iters)^power + end. After 'iters' iterations,
+// it stays at end, unless Cycle has been used. A power of 1 gives a linear decay.
+//
+// Polynomial will panic if iters < 1.
+func Polynomial(start, end float64, iters int, power float64) *polynomial {
+	if iters < 1 {
+		panic("given number of iterations is < 1")
+	}
+
+	return &polynomial{Start: start, End: end, Iters: iters, Power: power}
+}
+
+// Cycle changes the decay so that it restarts from start every 'iters' iterations, instead of
+// staying at end once it has finished.
+func (p *polynomial) Cycle() *polynomial {
+	p.Repeat = true
+	return p
+}
+
+func (p *polynomial) TypeString() string {
+	return "polynomial"
+}
+
+func (p *polynomial) Value(iter int) float64 {
+	if iter < 0 {
+		iter = 0
+	}
+
+	if p.Repeat {
+		iter %= p.Iters
+	} else if iter >= p.Iters {
+		return p.End
+	}
+
+	frac := 1 - float64(iter)/float64(p.Iters)
+	return (p.Start-p.End)*math.Pow(frac, p.Power) + p.End
+}
+
+func (p *polynomial) Get() interface{} {
+	return *p
+}
+
+func (p *polynomial) Blank() interface{} {
+	return p
+}
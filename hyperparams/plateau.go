@@ -0,0 +1,142 @@
+package hyperparams
+
+import (
+	bs "github.com/sharnoff/badstudent"
+	"math"
+)
+
+type plateau struct {
+	// the current value of the HyperParameter
+	Current float64
+
+	Factor   float64
+	Patience int
+	Min      float64
+
+	// the relative amount that the metric must improve by to be counted
+	RelThreshold float64
+
+	// the number of test results to ignore after each reduction
+	CooldownLen int
+
+	// whether or not the fraction correct is used, instead of the cost
+	UseCorrect bool
+
+	// the state of the HyperParameter, kept so that it can be saved and loaded.
+	Best         float64
+	HasBest      bool
+	NumBad       int
+	CooldownLeft int
+}
+
+// ReduceOnPlateau returns a HyperParameter that starts at 'initial' and is multiplied by 'factor'
+// whenever the test cost has not improved for more than 'patience' tests in a row. The test
+// results are given by Network.Train, so the HyperParameter will not change if the Network is not
+// being tested. Status updates are ignored.
+//
+// By default, any decrease in the test cost counts as an improvement, and the value can be reduced
+// indefinitely. These can be changed with Threshold and Minimum, respectively. Other options are
+// given by Cooldown and MaximizeCorrect.
+//
+// ReduceOnPlateau will panic if factor is not within the range (0, 1) or if patience < 0.
+//
+// The result of ReduceOnPlateau implements badstudent.Observer and badstudent.JSONAble.
+func ReduceOnPlateau(initial, factor float64, patience int) *plateau {
+	if factor <= 0 || factor >= 1 {
+		panic("given factor is not within the range (0, 1)")
+	} else if patience < 0 {
+		panic("given patience is < 0")
+	}
+
+	return &plateau{
+		Current:  initial,
+		Factor:   factor,
+		Patience: patience,
+	}
+}
+
+// Minimum sets the lowest value that the HyperParameter can be reduced to.
+func (p *plateau) Minimum(min float64) *plateau {
+	p.Min = min
+	return p
+}
+
+// Threshold sets the minimum relative improvement that counts as one. For example, with a
+// threshold of 0.01, the test cost must decrease by at least 1% of the best cost so far.
+//
+// Threshold will panic if t < 0.
+func (p *plateau) Threshold(t float64) *plateau {
+	if t < 0 {
+		panic("given threshold is < 0")
+	}
+
+	p.RelThreshold = t
+	return p
+}
+
+// Cooldown sets the number of tests after each reduction during which no tests are counted as
+// failing to improve.
+//
+// Cooldown will panic if tests < 0.
+func (p *plateau) Cooldown(tests int) *plateau {
+	if tests < 0 {
+		panic("given number of tests is < 0")
+	}
+
+	p.CooldownLen = tests
+	return p
+}
+
+// MaximizeCorrect changes the metric from the test cost to the fraction of test outputs that are
+// correct, which is counted as improving when it increases.
+func (p *plateau) MaximizeCorrect() *plateau {
+	p.UseCorrect = true
+	return p
+}
+
+func (p *plateau) TypeString() string {
+	return "plateau"
+}
+
+func (p *plateau) Value(iter int) float64 {
+	return p.Current
+}
+
+func (p *plateau) Observe(r bs.Result) {
+	if !r.IsTest {
+		return
+	}
+
+	// the metric is always minimized
+	metric := r.Cost
+	if p.UseCorrect {
+		metric = -r.Correct
+	}
+
+	if !p.HasBest || metric < p.Best-p.RelThreshold*math.Abs(p.Best) {
+		p.Best = metric
+		p.HasBest = true
+		p.NumBad = 0
+	} else {
+		p.NumBad++
+	}
+
+	if p.CooldownLeft > 0 {
+		p.CooldownLeft--
+		p.NumBad = 0
+	}
+
+	if p.NumBad > p.Patience {
+		p.Current = math.Max(p.Min, p.Current*p.Factor)
+		p.CooldownLeft = p.CooldownLen
+		p.NumBad = 0
+	}
+}
+
+func (p *plateau) Get() interface{} {
+	return *p
+}
+
+func (p *plateau) Blank() interface{} {
+	return p
+}
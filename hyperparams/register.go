@@ -6,6 +6,18 @@ func init() {
 	list := []interface{}{
 		func() bs.HyperParameter { return Constant(0) },
 		func() bs.HyperParameter { return Step(0) },
+		func() bs.HyperParameter { return Cosine(0, 0, 1) },
+		func() bs.HyperParameter { return Warmup(Constant(0), 1) },
+		func() bs.HyperParameter { return Sum() },
+		func() bs.HyperParameter { return Product() },
+		func() bs.HyperParameter { return Clamp(Constant(0), 0, 0) },
+		func() bs.HyperParameter { return Offset(Constant(0), 0) },
+		func() bs.HyperParameter { return Exponential(0, 0, 1) },
+		func() bs.HyperParameter { return InverseTime(0, 0, 1) },
+		func() bs.HyperParameter { return Polynomial(0, 0, 1, 1) },
+		func() bs.HyperParameter { return Cyclical(0, 0, 1) },
+		func() bs.HyperParameter { return OneCycle(0, 1) },
+		func() bs.HyperParameter { return ReduceOnPlateau(0, 0.5, 0) },
 	}
 
 	if err := bs.RegisterAll(list); err != nil {
@@ -139,9 +139,12 @@ type proxyNetwork struct {
 	OutputsID []int
 	NumNodes  int
 	Iter      int
+	LongIter  int
 	CFString  string
 	HPStrings map[string]string
 	PenString string
+	ClipValue float64
+	ClipNorm  float64
 }
 
 type proxyNode struct {
@@ -153,6 +156,7 @@ type proxyNode struct {
 	PenString string
 	InputsID  []int
 	Delay     int
+	ClipValue float64
 }
 
 func nodesToIDs(nodes []*Node) []int {
@@ -258,6 +262,68 @@ func loadElement(v interface{}, path string) error {
 	return nil
 }
 
+// NestedHP wraps a HyperParameter so that it can be saved and loaded as part of another
+// HyperParameter -- for those that are built out of others, such as hyperparams.Warmup. Because the
+// files for HyperParameters only record a single TypeString, NestedHP marshals to JSON with both
+// the TypeString and the contents of the HyperParameter it wraps, so that it can be reconstructed
+// from the registered types when it is loaded. This allows nesting to any depth.
+//
+// Only HyperParameters that are JSONAble (or have nothing to save) can be nested. Attempting to
+// save a NestedHP wrapping a Storable HyperParameter will give ErrNestedStorable.
+type NestedHP struct {
+	HyperParameter
+}
+
+// the JSON representation of a NestedHP
+type proxyHP struct {
+	TypeString string
+	Value      json.RawMessage `json:",omitempty"`
+}
+
+func (h NestedHP) MarshalJSON() ([]byte, error) {
+	if h.HyperParameter == nil {
+		return nil, NilArgError{"Nested HyperParameter"}
+	} else if _, ok := h.HyperParameter.(Storable); ok {
+		return nil, ErrNestedStorable
+	}
+
+	p := proxyHP{TypeString: h.TypeString()}
+	if j, ok := h.HyperParameter.(JSONAble); ok {
+		b, err := json.Marshal(j.Get())
+		if err != nil {
+			return nil, err
+		}
+
+		p.Value = b
+	}
+
+	return json.Marshal(p)
+}
+
+func (h *NestedHP) UnmarshalJSON(b []byte) error {
+	var p proxyHP
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+
+	var hp HyperParameter
+	var hpGen func() HyperParameter
+	if hpGen = hps[p.TypeString]; hpGen == nil {
+		return NotRegisteredError{"HyperParameter", p.TypeString}
+	} else if hp = hpGen(); hp == nil {
+		return ErrRegisterNilReturn
+	}
+
+	if j, ok := hp.(JSONAble); ok && len(p.Value) != 0 {
+		if err := json.Unmarshal(p.Value, j.Blank()); err != nil {
+			return err
+		}
+	}
+
+	h.HyperParameter = hp
+	return nil
+}
+
 // FieldIOError is a wrapper for other errors ocurring for saving or loading parts of a Network due
 // to direct interfacing with files.
 type FieldIOError struct {
@@ -289,7 +355,10 @@ func (net *Network) writeFile(dirPath string) error {
 		OutputsID: nodesToIDs(net.outputs.nodes),
 		NumNodes:  len(net.nodesByID),
 		Iter:      net.iter,
+		LongIter:  net.longIter,
 		CFString:  net.cf.TypeString(),
+		ClipValue: net.clipValue,
+		ClipNorm:  net.clipNorm,
 	}
 
 	if net.pen != nil {
@@ -343,15 +412,17 @@ func (n *Node) writeFile(dirPath string) error {
 			PenString string
 			InputsID  []int
 			Delay     int
+			ClipValue float64
 		}
 	*/
 
 	var p proxyNode
 	{
 		p = proxyNode{
-			Dims:  n.values.Dims,
-			Name:  n.name,
-			Delay: n.Delay(),
+			Dims:      n.values.Dims,
+			Name:      n.name,
+			Delay:     n.Delay(),
+			ClipValue: n.clipValue,
 		}
 
 		if !n.IsInput() {
@@ -482,6 +553,7 @@ func Load(path string) (*Network, error) {
 	net := new(Network)
 	pNodes := make([]proxyNode, pNet.NumNodes)
 	net.iter = pNet.Iter
+	net.longIter = pNet.LongIter
 
 	// Load the Nodes
 	for id := 0; id < pNet.NumNodes; id++ {
@@ -569,6 +641,10 @@ func Load(path string) (*Network, error) {
 		{
 			n.SetDelay(pn.Delay)
 
+			if pn.ClipValue != 0 {
+				n.ClipValue(pn.ClipValue)
+			}
+
 			if pn.OptString != "" {
 				var opt Optimizer
 				var optGen func() Optimizer
@@ -634,8 +710,16 @@ func Load(path string) (*Network, error) {
 		}
 	}
 
-	// set Network penalties and HyperParameters, if it has them:
+	// set Network penalties, HyperParameters and clipping, if it has them:
 	{
+		if pNet.ClipValue != 0 {
+			net.ClipValue(pNet.ClipValue)
+		}
+
+		if pNet.ClipNorm != 0 {
+			net.ClipNorm(pNet.ClipNorm)
+		}
+
 		if pNet.PenString != "" {
 			var pen Penalty
 			var penGen func() Penalty
@@ -69,6 +69,28 @@ func (n *Node) HasDelay() bool {
 	return n.Delay() != 0
 }
 
+// Training returns whether or not the Node is being evaluated as part of training. This is false
+// during Network.Test and GetOutputs, so that Operators that behave differently during training
+// (such as batch normalization) can tell which is the case.
+func (n *Node) Training() bool {
+	return n.host.training
+}
+
+// Recurrent returns whether or not the Node is part of a Network with delay or with Stateful
+// Operators, or one that has been combined by Bidirectional. In such Networks, each Node is
+// evaluated once for every time step of a sequence, and then again in reverse order while
+// backpropagating (see Replaying).
+func (n *Node) Recurrent() bool {
+	return n.host.hasDelay
+}
+
+// Replaying returns whether or not the Network is re-evaluating earlier time steps in order to
+// backpropagate through a recurrent Network. When this is the case, Nodes are given the same
+// inputs as they were originally, so Operators should not update any statistics that they keep.
+func (n *Node) Replaying() bool {
+	return n.host.isGettingDeltas()
+}
+
 // Size returns the number of values the Node produces.
 func (n *Node) Size() int {
 	return n.values.Size()
@@ -93,16 +115,46 @@ func (n *Node) Shape() tensors.Tensor {
 // HyperParameter is requested, HP will panic with ErrNoHP. This should only happen with custom
 // Optimizer types, which can be solved by proper usage of Optimizer.Needs().
 func (n *Node) HP(name string) float64 {
-	var hp HyperParameter
-	if hp = n.hyperParams[name]; hp == nil {
-		if hp = n.host.hyperParams[name]; hp == nil {
-			panic(ErrNoHP)
-		}
+	hp, ok := n.hp(name)
+	if !ok {
+		panic(ErrNoHP)
 	}
 
 	return hp.Value(n.host.longIter)
 }
 
+// Touched returns the indexes of the weights of the Node that may have non-zero gradients at the
+// current iteration, if its Operator is Sparse. If it is not -- or if the Node has a Penalty, which
+// changes the gradients of every weight -- Touched returns false, and all weights must be visited.
+func (n *Node) Touched() ([]int, bool) {
+	s, ok := n.op.(Sparse)
+	if !ok || n.pen != nil {
+		return nil, false
+	}
+
+	return s.Touched(n), true
+}
+
+// HasHP returns whether or not the Node has access to a HyperParameter with the given name, either
+// from the Node itself or from its Network. This allows Optimizers and Operators to make use of
+// HyperParameters that are optional.
+func (n *Node) HasHP(name string) bool {
+	_, ok := n.hp(name)
+	return ok
+}
+
+// hp returns the HyperParameter with the given name, first from the Node and then from the
+// Network, along with whether or not it was found.
+func (n *Node) hp(name string) (HyperParameter, bool) {
+	if hp := n.hyperParams[name]; hp != nil {
+		return hp, true
+	} else if hp := n.host.hyperParams[name]; hp != nil {
+		return hp, true
+	}
+
+	return nil, false
+}
+
 // Value returns the value of the Node at the specified (single-dimensional) index. Value will
 // allow panicking with index-out-of-bounds.
 func (n *Node) Value(index int) float64 {
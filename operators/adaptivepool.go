@@ -0,0 +1,270 @@
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"github.com/sharnoff/tensors"
+	"sort"
+)
+
+type adaptivePool struct {
+	// the output dimensions for adaptive pooling. Not used for global pooling
+	Target []int
+
+	// the dimensions that are pooled over entirely for global pooling, which are removed from the
+	// output. If none are given, it is every dimension except the last
+	Reduce []int
+
+	Global bool
+
+	// whether the maximum is used instead of the average
+	Max bool
+
+	// the dimensions of the inputs, and of the outputs before any are removed. Set by Finalize
+	ins, pooled *utils.MultiDim
+
+	// the index (in inputs) of the highest value for each output, for max pooling
+	switches []int
+}
+
+// GlobalAvgPool returns a pooling Operator that averages over the whole of the given dimensions of
+// its input, which implements badstudent.Operator. The pooled dimensions are removed from the
+// output. If no dimensions are given, every dimension except the last (the channels) is pooled, so
+// that an input with dimensions [width, height, channels] gives one value for each channel,
+// regardless of its width and height.
+//
+// If every dimension is pooled, the output has dimensions [1].
+func GlobalAvgPool(dims ...int) *adaptivePool {
+	return &adaptivePool{Reduce: dims, Global: true}
+}
+
+// GlobalMaxPool returns a pooling Operator that takes the maximum over the whole of the given
+// dimensions of its input, which implements badstudent.Operator. It is otherwise the same as
+// GlobalAvgPool.
+func GlobalMaxPool(dims ...int) *adaptivePool {
+	return &adaptivePool{Reduce: dims, Global: true, Max: true}
+}
+
+// AdaptiveAvgPool returns an average pooling Operator with the given output dimensions, which
+// implements badstudent.Operator. Unlike AvgPool, the filter and stride are calculated from the
+// dimensions of the input Node when it is finalized, so they do not need to be changed if the
+// input changes.
+//
+// Along each dimension, output 'i' is pooled from the inputs between floor(i * in / out) and
+// ceil((i+1) * in / out), so the regions may overlap or differ in size by one if the dimensions do
+// not divide evenly. Each output dimension must not be larger than the corresponding input
+// dimension.
+func AdaptiveAvgPool(dims ...int) *adaptivePool {
+	return &adaptivePool{Target: dims}
+}
+
+// AdaptiveMaxPool returns a max-pooling Operator with the given output dimensions, which
+// implements badstudent.Operator. It is otherwise the same as AdaptiveAvgPool.
+//
+// As with MaxPool, AdaptiveMaxPool cannot be used in a Node with delay.
+func AdaptiveMaxPool(dims ...int) *adaptivePool {
+	return &adaptivePool{Target: dims, Max: true}
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+// setDims sets the dimensions of the inputs and the pooled outputs, returning the dimensions of
+// the output
+func (p *adaptivePool) setDims(inputs []*bs.Node) ([]int, error) {
+	if len(inputs) != 1 {
+		return nil, errors.Errorf("Pooling must have exactly 1 input Node (has %d)", len(inputs))
+	}
+
+	ins := inputs[0].Dims()
+	pooled := make([]int, len(ins))
+
+	if p.Global {
+		reduce := p.Reduce
+		if len(reduce) == 0 {
+			// every dimension except the last, unless there's only one
+			num := len(ins) - 1
+			if num == 0 {
+				num = 1
+			}
+
+			for d := 0; d < num; d++ {
+				reduce = append(reduce, d)
+			}
+		}
+
+		copy(pooled, ins)
+		for _, d := range reduce {
+			if d < 0 || d >= len(ins) {
+				return nil, errors.Errorf("Dimension to pool over is out of range (%d not in [0, %d))", d, len(ins))
+			}
+
+			pooled[d] = 1
+		}
+
+		p.Reduce = append([]int(nil), reduce...)
+		sort.Ints(p.Reduce)
+	} else {
+		if len(p.Target) != len(ins) {
+			return nil, errors.Errorf("Number of output dimensions must equal the number of input dimensions (%d != %d)", len(p.Target), len(ins))
+		}
+
+		for d := range ins {
+			if p.Target[d] < 1 || p.Target[d] > ins[d] {
+				return nil, errors.Errorf("Output dimension #%d must be between 1 and the input dimension (%d not in [1, %d])", d, p.Target[d], ins[d])
+			}
+		}
+
+		copy(pooled, p.Target)
+	}
+
+	p.ins, p.pooled = utils.NewMultiDim(ins), utils.NewMultiDim(pooled)
+
+	if !p.Global {
+		return pooled, nil
+	}
+
+	// removing the pooled dimensions doesn't change the order of the outputs, because they all
+	// have size 1
+	var outs []int
+	for d := range pooled {
+		if i := sort.SearchInts(p.Reduce, d); i == len(p.Reduce) || p.Reduce[i] != d {
+			outs = append(outs, pooled[d])
+		}
+	}
+
+	if len(outs) == 0 {
+		outs = []int{1}
+	}
+
+	return outs, nil
+}
+
+// inputsTo returns the indexes of the inputs that are pooled for the given output
+func (p *adaptivePool) inputsTo(out int) []int {
+	out_p := p.pooled.Point(out)
+
+	start, size := make([]int, len(out_p)), make([]int, len(out_p))
+	for d := range out_p {
+		in, o := p.ins.Dim(d), p.pooled.Dim(d)
+
+		start[d] = out_p[d] * in / o
+		end := ((out_p[d]+1)*in + o - 1) / o
+		size[d] = end - start[d]
+	}
+
+	region := utils.NewMultiDim(size)
+	inList := make([]int, region.Size())
+
+	mod := make([]int, len(size))
+	for i := range inList {
+		in_p := make([]int, len(start))
+		copy(in_p, start)
+		inList[i] = p.ins.Index(mapAdd(in_p, mod))
+
+		region.Increment(mod)
+	}
+
+	return inList
+}
+
+// ***************************************************
+// Interface-required functions
+// ***************************************************
+
+func (p *adaptivePool) TypeString() string {
+	kind := "adaptive"
+	if p.Global {
+		kind = "global"
+	}
+
+	if p.Max {
+		return kind + "-max-pool"
+	}
+
+	return kind + "-avg-pool"
+}
+
+func (p *adaptivePool) Finalize(n *bs.Node) error {
+	if _, err := p.setDims(n.InputNodes()); err != nil {
+		return err
+	} else if p.Max && n.Delay() != 0 {
+		return errors.Errorf("MaxPooling cannot have delay (n.Delay() = %d)", n.Delay())
+	}
+
+	return nil
+}
+
+func (p *adaptivePool) Get() interface{} {
+	return *p
+}
+
+func (p *adaptivePool) Blank() interface{} {
+	return p
+}
+
+func (p *adaptivePool) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	dims, err := p.setDims(inputs)
+	if err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	return tensors.NewTensor(dims), nil
+}
+
+func (p *adaptivePool) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	if p.Max {
+		p.switches = make([]int, len(values))
+	}
+
+	f := func(v int) {
+		ins := p.inputsTo(v)
+
+		if !p.Max {
+			var sum float64
+			for _, in := range ins {
+				sum += inputs[in]
+			}
+
+			values[v] = sum / float64(len(ins))
+			return
+		}
+
+		p.switches[v] = ins[0]
+		for _, in := range ins[1:] {
+			if inputs[in] > inputs[p.switches[v]] {
+				p.switches[v] = in
+			}
+		}
+
+		values[v] = inputs[p.switches[v]]
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+}
+
+func (p *adaptivePool) InputDeltas(n *bs.Node) []float64 {
+	atoms := make([]uint64, n.NumInputs())
+
+	f := func(out int) {
+		if p.Max {
+			atomAdd(&atoms[p.switches[out]], n.Delta(out))
+			return
+		}
+
+		ins := p.inputsTo(out)
+		for _, in := range ins {
+			atomAdd(&atoms[in], n.Delta(out)/float64(len(ins)))
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, n.Size(), f, opsPerThread, threadsPerCPU)
+
+	return uint64ToFloat64(atoms)
+}
@@ -0,0 +1,444 @@
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"github.com/sharnoff/tensors"
+	"math"
+	"runtime"
+)
+
+// the kinds of arithmetic operators
+const (
+	arithAdd int = iota
+	arithMult
+	arithSub
+	arithDiv
+	arithMax
+	arithMin
+	arithPow
+)
+
+type arithmetic struct {
+	kind int
+
+	// whether or not the operator is Add or Mult loaded from the TypeString used before they could
+	// broadcast ("add" or "multiply"). If it is, inputs that all have the same size are still
+	// matched by index, as they were then, regardless of their dimensions.
+	legacy bool
+
+	// the shape of each input Node, or nil if it has the same size as the output. Set by Finalize
+	shapes []*utils.MultiDim
+
+	// the shape of the output, set by Finalize
+	outs *utils.MultiDim
+}
+
+// Add returns an elementwise addition operator that implements badstudent.Operator. Its Node may
+// have any number of inputs, which are broadcast together as described below.
+//
+// Broadcasting follows numpy: the dimensions of each input are aligned by their last dimension, and
+// each dimension of the output is the largest of the inputs in that dimension. Inputs must either
+// have the same size as the output in each dimension or have size 1, in which case that value is
+// used for every position along the dimension (missing dimensions are treated as size 1). Because
+// the last dimension is the channels, an input with dimensions [channels] can be broadcast onto
+// the output of Conv with dimensions [width, height, channels].
+//
+// Inputs are only matched by index without broadcasting if they all have the same dimensions, so
+// inputs with the same size but different dimensions must still be able to be broadcast together.
+//
+// Networks saved before Add could broadcast are loaded with the previous behavior, where inputs of
+// the same size are always matched by index.
+func Add() *arithmetic {
+	return &arithmetic{kind: arithAdd}
+}
+
+// Mult returns an elementwise multiplication operator that implements badstudent.Operator. Its Node
+// may have any number of inputs, which are broadcast together in the same way as with Add. As with
+// Add, Networks saved before Mult could broadcast are loaded with the previous behavior.
+func Mult() *arithmetic {
+	return &arithmetic{kind: arithMult}
+}
+
+// Mul is the same as Mult; it is provided for consistency with Sub and Div.
+func Mul() *arithmetic {
+	return Mult()
+}
+
+// Sub returns an operator that subtracts its second input from its first, which implements
+// badstudent.Operator. Its Node must have exactly two inputs, which are broadcast together in the
+// same way as with Add.
+func Sub() *arithmetic {
+	return &arithmetic{kind: arithSub}
+}
+
+// Div returns an operator that divides its first input by its second, which implements
+// badstudent.Operator. Its Node must have exactly two inputs, which are broadcast together in the
+// same way as with Add.
+//
+// Neither input is given deltas where the divisor is zero.
+func Div() *arithmetic {
+	return &arithmetic{kind: arithDiv}
+}
+
+// Max returns an operator that gives the maximum of its inputs, which implements
+// badstudent.Operator. Its Node may have any number of inputs, which are broadcast together in the
+// same way as with Add. Deltas are only given to the first input with the maximum value.
+func Max() *arithmetic {
+	return &arithmetic{kind: arithMax}
+}
+
+// Min returns an operator that gives the minimum of its inputs, which implements
+// badstudent.Operator. It is otherwise the same as Max.
+func Min() *arithmetic {
+	return &arithmetic{kind: arithMin}
+}
+
+// Pow returns an operator that raises its first input to the power of its second, which
+// implements badstudent.Operator. Its Node must have exactly two inputs, which are broadcast
+// together in the same way as with Add.
+//
+// The exponent is only given deltas where the base is positive, because the logarithm of the base
+// is not otherwise defined. Likewise, the base is not given deltas where its derivative is not
+// finite, such as for a base of zero with an exponent less than one.
+func Pow() *arithmetic {
+	return &arithmetic{kind: arithPow}
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+// binary returns whether or not the operator must have exactly two inputs
+func (t *arithmetic) binary() bool {
+	return t.kind == arithSub || t.kind == arithDiv || t.kind == arithPow
+}
+
+// setShapes sets the shapes of the inputs and the output, returning error if they cannot be
+// broadcast together
+func (t *arithmetic) setShapes(inputs []*bs.Node) error {
+	if t.binary() && len(inputs) != 2 {
+		return errors.Errorf("%s must have exactly 2 input Nodes (has %d)", t.TypeString(), len(inputs))
+	}
+
+	t.shapes = make([]*utils.MultiDim, len(inputs))
+
+	same := true
+	for _, in := range inputs {
+		if t.legacy {
+			same = same && in.Size() == inputs[0].Size()
+		} else {
+			same = same && equalDims(in.Dims(), inputs[0].Dims())
+		}
+	}
+
+	if same {
+		t.outs = utils.NewMultiDim(inputs[0].Dims())
+		return nil
+	}
+
+	var dims []int
+	for i, in := range inputs {
+		prev := append([]int(nil), dims...)
+		if len(in.Dims()) > len(dims) {
+			dims = append(make([]int, len(in.Dims())-len(dims)), dims...)
+		}
+
+		off := len(dims) - len(in.Dims())
+		for d, dim := range in.Dims() {
+			if dims[off+d] <= 1 {
+				dims[off+d] = dim
+			} else if dim != 1 && dim != dims[off+d] {
+				return errors.Errorf("Input #%d cannot be broadcast with the inputs before it (%v and %v)", i, in.Dims(), prev)
+			}
+		}
+	}
+
+	t.outs = utils.NewMultiDim(dims)
+	for i, in := range inputs {
+		if in.Size() != t.outs.Size() {
+			t.shapes[i] = utils.NewMultiDim(in.Dims())
+		}
+	}
+
+	return nil
+}
+
+// equalDims returns whether or not the two sets of dimensions are identical
+func equalDims(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for d := range a {
+		if a[d] != b[d] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// finite returns whether or not the value is neither infinite nor NaN
+func finite(x float64) bool {
+	return !math.IsInf(x, 0) && !math.IsNaN(x)
+}
+
+// sources returns the index of the value in each input given to the output at the given index,
+// relative to the start of each input
+func (t *arithmetic) sources(out int) []int {
+	srcs := make([]int, len(t.shapes))
+
+	var out_p []int
+	for i, s := range t.shapes {
+		if s == nil {
+			srcs[i] = out
+			continue
+		} else if out_p == nil {
+			out_p = t.outs.Point(out)
+		}
+
+		off := len(out_p) - len(s.Dims)
+		in_p := make([]int, len(s.Dims))
+		for d := range in_p {
+			if s.Dims[d] != 1 {
+				in_p[d] = out_p[off+d]
+			}
+		}
+
+		srcs[i] = s.Index(in_p)
+	}
+
+	return srcs
+}
+
+// operands returns the values from each input that are given to the output at the given index, in
+// addition to their indexes in the inputs to the Node
+func (t *arithmetic) operands(n *bs.Node, inputs []float64, out int) (xs []float64, idxs []int) {
+	idxs = t.sources(out)
+	xs = make([]float64, len(idxs))
+
+	var start int
+	for i := range idxs {
+		idxs[i] += start
+		xs[i] = inputs[idxs[i]]
+
+		start += n.Input(i).Size()
+	}
+
+	return xs, idxs
+}
+
+// extreme returns the index of the first maximum (or minimum) operand
+func (t *arithmetic) extreme(xs []float64) int {
+	best := 0
+	for i := 1; i < len(xs); i++ {
+		if (t.kind == arithMax && xs[i] > xs[best]) || (t.kind == arithMin && xs[i] < xs[best]) {
+			best = i
+		}
+	}
+
+	return best
+}
+
+// value returns the result of the operator for the given operands
+func (t *arithmetic) value(xs []float64) float64 {
+	switch t.kind {
+	case arithAdd:
+		var sum float64
+		for _, x := range xs {
+			sum += x
+		}
+		return sum
+	case arithMult:
+		prod := 1.0
+		for _, x := range xs {
+			prod *= x
+		}
+		return prod
+	case arithSub:
+		return xs[0] - xs[1]
+	case arithDiv:
+		return xs[0] / xs[1]
+	case arithMax, arithMin:
+		return xs[t.extreme(xs)]
+	default: // arithPow
+		return math.Pow(xs[0], xs[1])
+	}
+}
+
+// derivs returns the derivative of the result of the operator with respect to each operand
+func (t *arithmetic) derivs(xs []float64) []float64 {
+	ds := make([]float64, len(xs))
+
+	switch t.kind {
+	case arithAdd:
+		for i := range ds {
+			ds[i] = 1
+		}
+	case arithMult:
+		// the product of all of the other operands is calculated directly (instead of dividing the
+		// value by the operand) so that zeros are handled.
+		for i := range ds {
+			ds[i] = 1
+			for j, x := range xs {
+				if j != i {
+					ds[i] *= x
+				}
+			}
+		}
+	case arithSub:
+		ds[0], ds[1] = 1, -1
+	case arithDiv:
+		if xs[1] != 0 {
+			ds[0] = 1 / xs[1]
+			ds[1] = -xs[0] / (xs[1] * xs[1])
+		}
+	case arithMax, arithMin:
+		ds[t.extreme(xs)] = 1
+	case arithPow:
+		base, exp := xs[0], xs[1]
+		if d := exp * math.Pow(base, exp-1); exp != 0 && finite(d) {
+			ds[0] = d
+		}
+		if base > 0 {
+			ds[1] = math.Pow(base, exp) * math.Log(base)
+		}
+	}
+
+	return ds
+}
+
+// ***************************************************
+// Interface-required functions
+// ***************************************************
+
+func (t *arithmetic) TypeString() string {
+	if t.legacy {
+		return [...]string{"add", "multiply"}[t.kind]
+	}
+
+	return [...]string{"broadcast-add", "broadcast-multiply", "subtract", "divide", "maximum", "minimum", "power"}[t.kind]
+}
+
+func (t *arithmetic) Finalize(n *bs.Node) error {
+	return t.setShapes(n.InputNodes())
+}
+
+func (t *arithmetic) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	if err := t.setShapes(inputs); err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	return tensors.NewTensor(t.outs.Dims), nil
+}
+
+func (t *arithmetic) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	f := func(i int) {
+		xs, _ := t.operands(n, inputs, i)
+		values[i] = t.value(xs)
+	}
+
+	// just random constants. Have not been optimized
+	opsPerThread, threadsPerCPU := runtime.NumCPU()*2, 1
+	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+}
+
+func (t *arithmetic) InputDeltas(n *bs.Node) []float64 {
+	inputs := n.AllInputs()
+	atoms := make([]uint64, n.NumInputs())
+
+	f := func(i int) {
+		xs, idxs := t.operands(n, inputs, i)
+		for in, d := range t.derivs(xs) {
+			if d != 0 {
+				atomAdd(&atoms[idxs[in]], d*n.Delta(i))
+			}
+		}
+	}
+
+	// just random constants. Have not been optimized
+	opsPerThread, threadsPerCPU := runtime.NumCPU()*2, 1
+	utils.MultiThread(0, n.Size(), f, opsPerThread, threadsPerCPU)
+
+	return uint64ToFloat64(atoms)
+}
+
+// ****************************************
+// Scale
+// ****************************************
+
+type scale struct {
+	Factor float64
+}
+
+// Scale returns an elementwise operator that multiplies its inputs by a constant factor, which
+// implements badstudent.Operator.
+func Scale(factor float64) *scale {
+	return &scale{factor}
+}
+
+func (t *scale) TypeString() string {
+	return "scale"
+}
+
+func (t *scale) Finalize(n *bs.Node) error {
+	return nil
+}
+
+func (t *scale) Get() interface{} {
+	return *t
+}
+
+func (t *scale) Blank() interface{} {
+	return t
+}
+
+func (t *scale) Value(in float64, index int) float64 {
+	return t.Factor * in
+}
+
+func (t *scale) Deriv(n *bs.Node, index int) float64 {
+	return t.Factor
+}
+
+// ****************************************
+// Shift
+// ****************************************
+
+type shift struct {
+	Amount float64
+}
+
+// Shift returns an elementwise operator that adds a constant amount to its inputs, which implements
+// badstudent.Operator.
+func Shift(amount float64) *shift {
+	return &shift{amount}
+}
+
+func (t *shift) TypeString() string {
+	return "shift"
+}
+
+func (t *shift) Finalize(n *bs.Node) error {
+	return nil
+}
+
+func (t *shift) Get() interface{} {
+	return *t
+}
+
+func (t *shift) Blank() interface{} {
+	return t
+}
+
+func (t *shift) Value(in float64, index int) float64 {
+	return in + t.Amount
+}
+
+func (t *shift) Deriv(n *bs.Node, index int) float64 {
+	return 1
+}
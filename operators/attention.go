@@ -0,0 +1,393 @@
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"github.com/sharnoff/tensors"
+	"math"
+	"sync"
+)
+
+type attention struct {
+	Heads int
+
+	// the size of the projected queries, keys, and values (across all heads), and of the outputs
+	Dim int
+
+	// whether or not each query can only attend to keys at the same or earlier positions
+	Mask bool
+
+	// the projections for the queries, keys, values, and outputs, in that order. Each is stored as
+	// a matrix from input channels to output channels, followed by the biases: the weight from
+	// channel i to channel o is at [i*Dim + o].
+	Ws []float64
+
+	// the lengths of the queries and keys/values, and the number of channels of each input, which
+	// are set by Finalize
+	qLen, kLen  int
+	inChannels  [3]int
+	inputOffset [3]int
+
+	// from the most recent evaluation, all stored by row: the inputs, the projected queries, keys,
+	// and values, the attention weights for each head, and the concatenated outputs of the heads
+	xs      [3][]float64
+	proj    [3][]float64
+	weights []float64
+	concat  []float64
+
+	// the gradients of the weights and the deltas of the inputs, which are calculated only once for
+	// each evaluation, when they are first needed
+	once     *sync.Once
+	grads    []float64
+	inDeltas []float64
+}
+
+// the indexes of the inputs to attention
+const (
+	attnQuery int = iota
+	attnKey
+	attnValue
+)
+
+// Attention returns a multi-head scaled dot-product attention Layer, which implements
+// badstudent.Adjustable. Its Node must be given three input Nodes: the queries, keys, and values,
+// in that order. Each must have two dimensions: [length, channels], as with the outputs of
+// Embedding. The keys and values must have the same length. For self-attention, the same Node can
+// be given for all three.
+//
+// The queries, keys, and values are each projected to 'dim' channels (with biases), which are
+// split evenly between the heads. The outputs of the heads are concatenated and projected again,
+// so the output of the Node has dimensions [query length, dim].
+//
+// Queries can be restricted to only attend to earlier positions with Causal.
+//
+// For more information, see: https://arxiv.org/abs/1706.03762
+func Attention(heads, dim int) *attention {
+	return &attention{Heads: heads, Dim: dim}
+}
+
+// Causal adds causal masking, so that each query can only attend to the keys at the same or
+// earlier positions. With Causal, the queries and keys must have the same length.
+func (a *attention) Causal() *attention {
+	a.Mask = true
+	return a
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+// headDim returns the number of channels in each head
+func (a *attention) headDim() int {
+	return a.Dim / a.Heads
+}
+
+// weightOffset returns the index in Ws at which the projection for the given input starts. The
+// output projection is given by weightOffset(3).
+func (a *attention) weightOffset(proj int) int {
+	off := 0
+	for i := 0; i < proj; i++ {
+		off += (a.inChannels[i] + 1) * a.Dim
+	}
+
+	return off
+}
+
+// length returns the length of the given input
+func (a *attention) length(input int) int {
+	if input == attnQuery {
+		return a.qLen
+	}
+
+	return a.kLen
+}
+
+// setShapes sets the lengths and channels of the inputs, returning error if they are invalid
+func (a *attention) setShapes(inputs []*bs.Node) error {
+	if len(inputs) != 3 {
+		return errors.Errorf("Attention must have 3 input Nodes (has %d)", len(inputs))
+	}
+
+	off := 0
+	for i, in := range inputs {
+		dims := in.Dims()
+		if len(dims) != 2 {
+			return errors.Errorf("Input %d must have 2 dimensions (has %d)", i, len(dims))
+		}
+
+		a.inChannels[i] = dims[1]
+		a.inputOffset[i] = off
+		off += in.Size()
+	}
+
+	a.qLen = inputs[attnQuery].Dims()[0]
+	a.kLen = inputs[attnKey].Dims()[0]
+	if inputs[attnValue].Dims()[0] != a.kLen {
+		return errors.Errorf("Keys and values must have the same length (%d != %d)", a.kLen, inputs[attnValue].Dims()[0])
+	} else if a.Mask && a.qLen != a.kLen {
+		return errors.Errorf("Causal attention must have queries and keys of the same length (%d != %d)", a.qLen, a.kLen)
+	}
+
+	return nil
+}
+
+// project multiplies each of the 'rows' rows of x (with 'in' channels) by the matrix (followed by
+// biases) given by w, returning the resulting rows of Dim channels.
+func (a *attention) project(x []float64, rows, in int, w []float64) []float64 {
+	out := make([]float64, rows*a.Dim)
+	bias := w[in*a.Dim:]
+
+	f := func(r int) {
+		for o := 0; o < a.Dim; o++ {
+			sum := bias[o]
+			for i := 0; i < in; i++ {
+				sum += x[r*in+i] * w[i*a.Dim+o]
+			}
+
+			out[r*a.Dim+o] = sum
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, rows, f, opsPerThread, threadsPerCPU)
+	return out
+}
+
+// projectBack is the reverse of project: given the deltas of its outputs, it adds to the gradients
+// of w (given by gw) and returns the deltas of x.
+func (a *attention) projectBack(x, ds []float64, rows, in int, w, gw []float64) []float64 {
+	dx := make([]float64, rows*in)
+
+	f := func(i int) {
+		for r := 0; r < rows; r++ {
+			for o := 0; o < a.Dim; o++ {
+				gw[i*a.Dim+o] += x[r*in+i] * ds[r*a.Dim+o]
+				dx[r*in+i] += ds[r*a.Dim+o] * w[i*a.Dim+o]
+			}
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, in, f, opsPerThread, threadsPerCPU)
+
+	for r := 0; r < rows; r++ {
+		for o := 0; o < a.Dim; o++ {
+			gw[in*a.Dim+o] += ds[r*a.Dim+o]
+		}
+	}
+
+	return dx
+}
+
+// backward calculates the gradients of all of the weights, and the deltas of the inputs
+func (a *attention) backward(n *bs.Node) {
+	a.grads = make([]float64, len(a.Ws))
+
+	// deltas of the outputs, by row
+	dOut := make([]float64, a.qLen*a.Dim)
+	for t := 0; t < a.qLen; t++ {
+		for o := 0; o < a.Dim; o++ {
+			dOut[t*a.Dim+o] = n.Delta(o*a.qLen + t)
+		}
+	}
+
+	off := a.weightOffset(3)
+	dConcat := a.projectBack(a.concat, dOut, a.qLen, a.Dim, a.Ws[off:], a.grads[off:])
+
+	var dProj [3][]float64
+	for i := range dProj {
+		dProj[i] = make([]float64, a.length(i)*a.Dim)
+	}
+
+	hd := a.headDim()
+	scale := 1 / math.Sqrt(float64(hd))
+	q, k, v := a.proj[attnQuery], a.proj[attnKey], a.proj[attnValue]
+
+	// each head only uses its own channels, so they can be done in parallel
+	f := func(h int) {
+		wts := a.weights[h*a.qLen*a.kLen:]
+		dScore := make([]float64, a.kLen)
+
+		for i := 0; i < a.qLen; i++ {
+			dc := dConcat[i*a.Dim+h*hd : i*a.Dim+(h+1)*hd]
+
+			// the deltas of the attention weights, and their weighted sum for the softmax
+			var sum float64
+			for j := 0; j < a.kLen; j++ {
+				w := wts[i*a.kLen+j]
+
+				var d float64
+				for c := range dc {
+					d += dc[c] * v[j*a.Dim+h*hd+c]
+					dProj[attnValue][j*a.Dim+h*hd+c] += w * dc[c]
+				}
+
+				dScore[j] = d
+				sum += w * d
+			}
+
+			for j := 0; j < a.kLen; j++ {
+				ds := wts[i*a.kLen+j] * (dScore[j] - sum) * scale
+				if ds == 0 {
+					continue
+				}
+
+				for c := 0; c < hd; c++ {
+					dProj[attnQuery][i*a.Dim+h*hd+c] += ds * k[j*a.Dim+h*hd+c]
+					dProj[attnKey][j*a.Dim+h*hd+c] += ds * q[i*a.Dim+h*hd+c]
+				}
+			}
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, a.Heads, f, opsPerThread, threadsPerCPU)
+
+	a.inDeltas = make([]float64, n.NumInputs())
+	for i := range dProj {
+		off := a.weightOffset(i)
+		ch, length := a.inChannels[i], a.length(i)
+		dx := a.projectBack(a.xs[i], dProj[i], length, ch, a.Ws[off:], a.grads[off:])
+
+		// the inputs are stored with the positions changing fastest
+		for t := 0; t < length; t++ {
+			for c := 0; c < ch; c++ {
+				a.inDeltas[a.inputOffset[i]+c*length+t] += dx[t*ch+c]
+			}
+		}
+	}
+}
+
+// ***************************************************
+// Interface-required functions
+// ***************************************************
+
+func (a *attention) TypeString() string {
+	return "attention"
+}
+
+func (a *attention) Finalize(n *bs.Node) error {
+	if a.Heads < 1 {
+		return errors.Errorf("Number of heads must be ≥ 1 (%d)", a.Heads)
+	} else if a.Dim < 1 || a.Dim%a.Heads != 0 {
+		return errors.Errorf("Dimension must be a positive multiple of the number of heads (%d, %d heads)", a.Dim, a.Heads)
+	}
+
+	if err := a.setShapes(n.InputNodes()); err != nil {
+		return err
+	}
+
+	a.once = new(sync.Once)
+
+	// if it's been loaded from a file...
+	if len(a.Ws) != 0 {
+		return nil
+	}
+
+	a.Ws = make([]float64, a.weightOffset(3)+(a.Dim+1)*a.Dim)
+	return nil
+}
+
+func (a *attention) Get() interface{} {
+	return *a
+}
+
+func (a *attention) Blank() interface{} {
+	return a
+}
+
+func (a *attention) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	if err := a.setShapes(inputs); err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	return tensors.NewTensor([]int{a.qLen, a.Dim}), nil
+}
+
+func (a *attention) Evaluate(n *bs.Node, values []float64) {
+	a.once = new(sync.Once)
+
+	inputs := n.AllInputs()
+	for i := range a.xs {
+		ch, length := a.inChannels[i], a.length(i)
+		x := make([]float64, length*ch)
+		for t := 0; t < length; t++ {
+			for c := 0; c < ch; c++ {
+				x[t*ch+c] = inputs[a.inputOffset[i]+c*length+t]
+			}
+		}
+
+		a.xs[i] = x
+		a.proj[i] = a.project(x, length, ch, a.Ws[a.weightOffset(i):])
+	}
+
+	hd := a.headDim()
+	scale := 1 / math.Sqrt(float64(hd))
+	q, k, v := a.proj[attnQuery], a.proj[attnKey], a.proj[attnValue]
+
+	a.weights = make([]float64, a.Heads*a.qLen*a.kLen)
+	a.concat = make([]float64, a.qLen*a.Dim)
+
+	f := func(h int) {
+		wts := a.weights[h*a.qLen*a.kLen:]
+
+		for i := 0; i < a.qLen; i++ {
+			end := a.kLen
+			if a.Mask {
+				end = i + 1
+			}
+
+			// softmax of the scores, subtracting the maximum for stability
+			max := math.Inf(-1)
+			for j := 0; j < end; j++ {
+				var s float64
+				for c := 0; c < hd; c++ {
+					s += q[i*a.Dim+h*hd+c] * k[j*a.Dim+h*hd+c]
+				}
+
+				wts[i*a.kLen+j] = s * scale
+				max = math.Max(max, s*scale)
+			}
+
+			var sum float64
+			for j := 0; j < end; j++ {
+				wts[i*a.kLen+j] = math.Exp(wts[i*a.kLen+j] - max)
+				sum += wts[i*a.kLen+j]
+			}
+
+			for j := 0; j < end; j++ {
+				w := wts[i*a.kLen+j] / sum
+				wts[i*a.kLen+j] = w
+
+				for c := 0; c < hd; c++ {
+					a.concat[i*a.Dim+h*hd+c] += w * v[j*a.Dim+h*hd+c]
+				}
+			}
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, a.Heads, f, opsPerThread, threadsPerCPU)
+
+	out := a.project(a.concat, a.qLen, a.Dim, a.Ws[a.weightOffset(3):])
+	for t := 0; t < a.qLen; t++ {
+		for o := 0; o < a.Dim; o++ {
+			values[o*a.qLen+t] = out[t*a.Dim+o]
+		}
+	}
+}
+
+func (a *attention) InputDeltas(n *bs.Node) []float64 {
+	a.once.Do(func() { a.backward(n) })
+	return a.inDeltas
+}
+
+func (a *attention) Grad(n *bs.Node, index int) float64 {
+	a.once.Do(func() { a.backward(n) })
+	return a.grads[index]
+}
+
+func (a *attention) Weights() []float64 {
+	return a.Ws
+}
@@ -0,0 +1,207 @@
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/tensors"
+	"sync"
+)
+
+type bidirectional struct {
+	// the type of cell used in each direction. Its weights are not used; they are stored in Ws
+	Cell cell
+
+	// the weights of the forwards cell, followed by the weights of the backwards cell
+	Ws []float64
+
+	// the cells for each direction, which share their weights with Ws. Set by Finalize
+	forwards, backwards *cell
+
+	// the length of the sequence and the number of channels at each step, set by Finalize
+	length, channels int
+
+	// the time steps from the most recent evaluation, in the order that each cell evaluated them
+	fSteps, bSteps []cellStep
+
+	// the gradients of the weights and the deltas of the inputs, which are calculated only once for
+	// each evaluation, when they are first needed
+	once     *sync.Once
+	grads    []float64
+	inDeltas []float64
+}
+
+// Bidirectional returns a Layer that runs a recurrent cell over an entire sequence at once, both
+// forwards and backwards, which implements badstudent.Adjustable. The cell must be given by
+// LSTMCell or GRUCell; each direction has its own weights.
+//
+// Because the whole sequence is given at once, the Network does not need to be recurrent. The
+// input to its Node must have two dimensions: [length, channels], as with the outputs of
+// Embedding, where each position is a time step. The output has dimensions [length, 2 * size],
+// with the states of the forwards cell in the first 'size' channels and the states of the
+// backwards cell in the rest, so that the outputs at each position have context from the whole
+// sequence.
+//
+// To process Sequential data one time step at a time instead, with any sub-graph in each
+// direction, see badstudent.Bidirectional.
+//
+// For more information, see: https://ieeexplore.ieee.org/document/650093
+func Bidirectional(c *cell) *bidirectional {
+	return &bidirectional{Cell: cell{Size: c.Size, Gated: c.Gated}}
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+// run evaluates the cell over every time step, in the given order, returning the steps
+func (b *bidirectional) run(c *cell, inputs []float64, order []int) []cellStep {
+	steps := make([]cellStep, len(order))
+
+	hidden, cellState := make([]float64, c.Size), make([]float64, c.Size)
+	for i, t := range order {
+		st := cellStep{inputs: make([]float64, b.channels), hidden: hidden, cellState: cellState}
+		for ch := range st.inputs {
+			st.inputs[ch] = inputs[ch*b.length+t]
+		}
+
+		c.evaluate(&st)
+		steps[i] = st
+
+		hidden = st.newHidden
+		if !c.Gated {
+			cellState = st.newCell
+		}
+	}
+
+	return steps
+}
+
+// backprop backpropagates through the steps given by run, where the deltas of each time step start
+// at the given channel
+func (b *bidirectional) backprop(n *bs.Node, c *cell, steps []cellStep, order []int, channel int, grads []float64) {
+	var carryHidden, carryCell []float64
+	for i := len(steps) - 1; i >= 0; i-- {
+		t := order[i]
+
+		dHidden := make([]float64, c.Size)
+		for v := range dHidden {
+			dHidden[v] = n.Delta((channel+v)*b.length + t)
+			if carryHidden != nil {
+				dHidden[v] += carryHidden[v]
+			}
+		}
+
+		var ds []float64
+		ds, carryHidden, carryCell = c.backprop(steps[i], dHidden, carryCell, grads)
+		for ch, d := range ds {
+			b.inDeltas[ch*b.length+t] += d
+		}
+	}
+}
+
+// order returns the order of the time steps for each direction
+func (b *bidirectional) order() (forwards, backwards []int) {
+	forwards, backwards = make([]int, b.length), make([]int, b.length)
+	for t := range forwards {
+		forwards[t] = t
+		backwards[t] = b.length - 1 - t
+	}
+
+	return
+}
+
+func (b *bidirectional) backward(n *bs.Node) {
+	b.grads = make([]float64, len(b.Ws))
+	b.inDeltas = make([]float64, n.NumInputs())
+
+	split := len(b.forwards.Ws)
+	fOrder, bOrder := b.order()
+	b.backprop(n, b.forwards, b.fSteps, fOrder, 0, b.grads[:split])
+	b.backprop(n, b.backwards, b.bSteps, bOrder, b.Cell.Size, b.grads[split:])
+}
+
+// ***************************************************
+// Interface-required functions
+// ***************************************************
+
+func (b *bidirectional) TypeString() string {
+	return "bidirectional"
+}
+
+func (b *bidirectional) Finalize(n *bs.Node) error {
+	if b.Cell.Size < 1 {
+		return errors.Errorf("Size must be ≥ 1 (%d)", b.Cell.Size)
+	}
+
+	if n.NumInputNodes() != 1 {
+		return errors.Errorf("Bidirectional must have exactly 1 input Node (has %d)", n.NumInputNodes())
+	}
+
+	dims := n.Input(0).Dims()
+	if len(dims) != 2 {
+		return errors.Errorf("Input must have 2 dimensions (has %d)", len(dims))
+	}
+
+	b.length, b.channels = dims[0], dims[1]
+	b.once = new(sync.Once)
+
+	// if it hasn't been loaded from a file...
+	split := b.Cell.numWeights(b.channels)
+	if len(b.Ws) == 0 {
+		b.Ws = make([]float64, 2*split)
+	}
+
+	b.forwards = &cell{Size: b.Cell.Size, Gated: b.Cell.Gated, Ws: b.Ws[:split]}
+	b.backwards = &cell{Size: b.Cell.Size, Gated: b.Cell.Gated, Ws: b.Ws[split:]}
+	return nil
+}
+
+func (b *bidirectional) Get() interface{} {
+	return *b
+}
+
+func (b *bidirectional) Blank() interface{} {
+	return b
+}
+
+func (b *bidirectional) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	dims, err := inputDims("Bidirectional", inputs)
+	if err != nil {
+		return tensors.Tensor{}, err
+	} else if len(dims) != 2 {
+		return tensors.Tensor{}, errors.Errorf("Input must have 2 dimensions (has %d)", len(dims))
+	}
+
+	return tensors.NewTensor([]int{dims[0], 2 * b.Cell.Size}), nil
+}
+
+func (b *bidirectional) Evaluate(n *bs.Node, values []float64) {
+	b.once = new(sync.Once)
+
+	inputs := n.AllInputs()
+	fOrder, bOrder := b.order()
+	b.fSteps = b.run(b.forwards, inputs, fOrder)
+	b.bSteps = b.run(b.backwards, inputs, bOrder)
+
+	size := b.Cell.Size
+	for i := range fOrder {
+		for v := 0; v < size; v++ {
+			values[v*b.length+fOrder[i]] = b.fSteps[i].newHidden[v]
+			values[(size+v)*b.length+bOrder[i]] = b.bSteps[i].newHidden[v]
+		}
+	}
+}
+
+func (b *bidirectional) InputDeltas(n *bs.Node) []float64 {
+	b.once.Do(func() { b.backward(n) })
+	return b.inDeltas
+}
+
+func (b *bidirectional) Grad(n *bs.Node, index int) float64 {
+	b.once.Do(func() { b.backward(n) })
+	return b.grads[index]
+}
+
+func (b *bidirectional) Weights() []float64 {
+	return b.Ws
+}
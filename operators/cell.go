@@ -0,0 +1,392 @@
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"github.com/sharnoff/tensors"
+	"math"
+	"sync"
+)
+
+type cell struct {
+	Size int
+
+	// whether the cell is a GRU instead of an LSTM
+	Gated bool
+
+	// the weights of each gate, organized in (number of gates * Size) sets of:
+	// input, ... input, hidden, ... hidden, bias
+	//
+	// The gates are in the order: forget, ignore, update, select for LSTMs, and update, reset,
+	// candidate for GRUs.
+	Ws []float64
+
+	// the state carried between time steps: the hidden state and, for LSTMs, the cell-state
+	hidden, cellState []float64
+
+	// steps stores the time steps of the current sequence while training, so that they can be
+	// used again while backpropagating
+	steps []cellStep
+
+	// the most recently evaluated (or replayed) time step
+	cur cellStep
+
+	// the deltas of the states from the later time step that has just been backpropagated
+	carryHidden, carryCell []float64
+
+	// the gradients of the weights and the deltas of the inputs for the current time step, which
+	// are calculated only once, when they are first needed
+	once     *sync.Once
+	grads    []float64
+	inDeltas []float64
+}
+
+// cellStep is everything that the cell needs from a single time step in order to backpropagate
+// through it
+type cellStep struct {
+	inputs []float64
+
+	// the states from the previous time step
+	hidden, cellState []float64
+
+	// the activations of each gate
+	gates []float64
+
+	// the new states. For GRUs, newCell is the reset hidden state, which is given to the candidate
+	newHidden, newCell []float64
+}
+
+// the indexes of the gates in each type of cell
+const (
+	lstmForget int = iota
+	lstmIgnore
+	lstmUpdate
+	lstmSelect
+	lstmGates
+)
+
+const (
+	gruUpdate int = iota
+	gruReset
+	gruCandidate
+	gruGates
+)
+
+// LSTMCell returns a fused LSTM Layer, which implements badstudent.Adjustable and
+// badstudent.Stateful. It gives the same results as building an LSTM with operators.LSTM, where
+// each gate is made of Neurons and Logistic (or Tanh, for the update) with the input to the Node
+// and the previous outputs as their inputs. All of the weights are kept in a single Node, which is
+// much faster than evaluating each part separately.
+//
+// The hidden state and cell-state are kept by the Layer itself, so its Node does not need delay,
+// and the outputs of the Node are the hidden state at each time step. Networks with LSTMCell are
+// always recurrent.
+//
+// For more information, see: https://www.bioinf.jku.at/publications/older/2604.pdf
+func LSTMCell(size int) *cell {
+	return &cell{Size: size}
+}
+
+// GRUCell returns a fused GRU Layer, which implements badstudent.Adjustable and
+// badstudent.Stateful. It gives the same results as GRU, but with all of the weights kept in a
+// single Node. Otherwise, it is the same as LSTMCell.
+//
+// For more information, see: https://arxiv.org/abs/1406.1078
+func GRUCell(size int) *cell {
+	return &cell{Size: size, Gated: true}
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+func (c *cell) numGates() int {
+	if c.Gated {
+		return gruGates
+	}
+
+	return lstmGates
+}
+
+// stride returns the number of weights for each unit of each gate, given the number of inputs
+func (c *cell) stride(numIn int) int {
+	return numIn + c.Size + 1
+}
+
+// numWeights returns the total number of weights, given the number of inputs
+func (c *cell) numWeights(numIn int) int {
+	return c.numGates() * c.Size * c.stride(numIn)
+}
+
+func logisticOf(x float64) float64 {
+	return 0.5 + 0.5*math.Tanh(0.5*x)
+}
+
+// sums sets the weighted sums for the given gates, from the inputs and some hidden values
+func (c *cell) sums(sums []float64, gates []int, inputs, hidden []float64) {
+	stride := c.stride(len(inputs))
+
+	f := func(i int) {
+		row := gates[i/c.Size]*c.Size + i%c.Size
+		ws := c.Ws[row*stride : (row+1)*stride]
+
+		sum := ws[stride-1]
+		for j := range inputs {
+			sum += ws[j] * inputs[j]
+		}
+		for j := range hidden {
+			sum += ws[len(inputs)+j] * hidden[j]
+		}
+
+		sums[row] = sum
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(gates)*c.Size, f, opsPerThread, threadsPerCPU)
+}
+
+// evaluate calculates a single time step from the previous state
+func (c *cell) evaluate(st *cellStep) {
+	size := c.Size
+	st.gates = make([]float64, c.numGates()*size)
+	st.newHidden = make([]float64, size)
+	st.newCell = make([]float64, size)
+
+	if !c.Gated {
+		c.sums(st.gates, []int{lstmForget, lstmIgnore, lstmUpdate, lstmSelect}, st.inputs, st.hidden)
+
+		for v := 0; v < size; v++ {
+			f := logisticOf(st.gates[lstmForget*size+v])
+			i := logisticOf(st.gates[lstmIgnore*size+v])
+			u := math.Tanh(st.gates[lstmUpdate*size+v])
+			s := logisticOf(st.gates[lstmSelect*size+v])
+			st.gates[lstmForget*size+v], st.gates[lstmIgnore*size+v] = f, i
+			st.gates[lstmUpdate*size+v], st.gates[lstmSelect*size+v] = u, s
+
+			st.newCell[v] = f*st.cellState[v] + i*u
+			st.newHidden[v] = s * math.Tanh(st.newCell[v])
+		}
+
+		return
+	}
+
+	c.sums(st.gates, []int{gruUpdate, gruReset}, st.inputs, st.hidden)
+	for v := 0; v < 2*size; v++ {
+		st.gates[v] = logisticOf(st.gates[v])
+	}
+
+	for v := 0; v < size; v++ {
+		st.newCell[v] = st.gates[gruReset*size+v] * st.hidden[v]
+	}
+
+	c.sums(st.gates, []int{gruCandidate}, st.inputs, st.newCell)
+	for v := 0; v < size; v++ {
+		z := st.gates[gruUpdate*size+v]
+		cand := math.Tanh(st.gates[gruCandidate*size+v])
+		st.gates[gruCandidate*size+v] = cand
+
+		st.newHidden[v] = (1-z)*st.hidden[v] + z*cand
+	}
+}
+
+// backprop backpropagates through a single time step, given the deltas of its new states. The
+// deltas of the cell-state may be nil. It adds to the gradients given, and returns the deltas of
+// the inputs and of the previous states.
+func (c *cell) backprop(st cellStep, dHidden, dCellState, grads []float64) (inDeltas, prevHidden, prevCell []float64) {
+	size := c.Size
+	numIn := len(st.inputs)
+	stride := c.stride(numIn)
+
+	// the deltas of the weighted sum of each gate
+	dSums := make([]float64, len(st.gates))
+	prevHidden = make([]float64, size)
+	prevCell = make([]float64, size)
+
+	if !c.Gated {
+		for v := 0; v < size; v++ {
+			f, i := st.gates[lstmForget*size+v], st.gates[lstmIgnore*size+v]
+			u, s := st.gates[lstmUpdate*size+v], st.gates[lstmSelect*size+v]
+			tc := math.Tanh(st.newCell[v])
+
+			dCell := dHidden[v] * s * (1 - tc*tc)
+			if dCellState != nil {
+				dCell += dCellState[v]
+			}
+
+			dSums[lstmForget*size+v] = dCell * st.cellState[v] * f * (1 - f)
+			dSums[lstmIgnore*size+v] = dCell * u * i * (1 - i)
+			dSums[lstmUpdate*size+v] = dCell * i * (1 - u*u)
+			dSums[lstmSelect*size+v] = dHidden[v] * tc * s * (1 - s)
+
+			prevCell[v] = dCell * f
+		}
+	} else {
+		for v := 0; v < size; v++ {
+			z, cand := st.gates[gruUpdate*size+v], st.gates[gruCandidate*size+v]
+
+			dSums[gruUpdate*size+v] = dHidden[v] * (cand - st.hidden[v]) * z * (1 - z)
+			dSums[gruCandidate*size+v] = dHidden[v] * z * (1 - cand*cand)
+			prevHidden[v] = dHidden[v] * (1 - z)
+		}
+
+		// the reset gate is given deltas through the candidate's weights on the reset hidden state
+		for j := 0; j < size; j++ {
+			var d float64
+			for v := 0; v < size; v++ {
+				d += dSums[gruCandidate*size+v] * c.Ws[(gruCandidate*size+v)*stride+numIn+j]
+			}
+
+			r := st.gates[gruReset*size+j]
+			prevHidden[j] += d * r
+			dSums[gruReset*size+j] = d * st.hidden[j] * r * (1 - r)
+		}
+	}
+
+	// with the deltas of the sums, the gradients and deltas of the inputs are the same as for
+	// Neurons
+	f := func(row int) {
+		hidden := st.hidden
+		if c.Gated && row/size == gruCandidate {
+			hidden = st.newCell
+		}
+
+		g := grads[row*stride : (row+1)*stride]
+		for j, x := range st.inputs {
+			g[j] += dSums[row] * x
+		}
+		for j, h := range hidden {
+			g[numIn+j] += dSums[row] * h
+		}
+		g[stride-1] += dSums[row]
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(dSums), f, opsPerThread, threadsPerCPU)
+
+	inDeltas = make([]float64, numIn)
+	for row, d := range dSums {
+		ws := c.Ws[row*stride : (row+1)*stride]
+		for j := range inDeltas {
+			inDeltas[j] += d * ws[j]
+		}
+
+		// the candidate's hidden weights were handled with the reset gate
+		if c.Gated && row/size == gruCandidate {
+			continue
+		}
+
+		for j := range prevHidden {
+			prevHidden[j] += d * ws[numIn+j]
+		}
+	}
+
+	return inDeltas, prevHidden, prevCell
+}
+
+// backward calculates the gradients and input deltas for the current time step, and carries the
+// deltas of the previous states back to the time step before it
+func (c *cell) backward(n *bs.Node) {
+	dHidden := make([]float64, c.Size)
+	for v := range dHidden {
+		dHidden[v] = n.Delta(v)
+		if c.carryHidden != nil {
+			dHidden[v] += c.carryHidden[v]
+		}
+	}
+
+	c.grads = make([]float64, len(c.Ws))
+	c.inDeltas, c.carryHidden, c.carryCell = c.backprop(c.cur, dHidden, c.carryCell, c.grads)
+}
+
+// ***************************************************
+// Interface-required functions
+// ***************************************************
+
+func (c *cell) TypeString() string {
+	if c.Gated {
+		return "gru-cell"
+	}
+
+	return "lstm-cell"
+}
+
+func (c *cell) Finalize(n *bs.Node) error {
+	if c.Size < 1 {
+		return errors.Errorf("Size must be ≥ 1 (%d)", c.Size)
+	}
+
+	c.ResetState(n)
+
+	// if it's been loaded from a file...
+	if len(c.Ws) != 0 {
+		return nil
+	}
+
+	c.Ws = make([]float64, c.numWeights(n.NumInputs()))
+	return nil
+}
+
+func (c *cell) Get() interface{} {
+	return *c
+}
+
+func (c *cell) Blank() interface{} {
+	return c
+}
+
+func (c *cell) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	return tensors.NewTensor([]int{c.Size}), nil
+}
+
+func (c *cell) Evaluate(n *bs.Node, values []float64) {
+	c.once = new(sync.Once)
+
+	if n.Replaying() {
+		c.cur = c.steps[len(c.steps)-1]
+		c.steps = c.steps[:len(c.steps)-1]
+		copy(values, c.cur.newHidden)
+		return
+	}
+
+	st := cellStep{
+		inputs:    append([]float64(nil), n.AllInputs()...),
+		hidden:    c.hidden,
+		cellState: c.cellState,
+	}
+
+	c.evaluate(&st)
+	c.cur = st
+	copy(values, st.newHidden)
+
+	c.hidden = st.newHidden
+	if !c.Gated {
+		c.cellState = st.newCell
+	}
+
+	if n.Training() {
+		c.steps = append(c.steps, st)
+		c.carryHidden, c.carryCell = nil, nil
+	}
+}
+
+func (c *cell) InputDeltas(n *bs.Node) []float64 {
+	c.once.Do(func() { c.backward(n) })
+	return c.inDeltas
+}
+
+func (c *cell) Grad(n *bs.Node, index int) float64 {
+	c.once.Do(func() { c.backward(n) })
+	return c.grads[index]
+}
+
+func (c *cell) Weights() []float64 {
+	return c.Ws
+}
+
+func (c *cell) ResetState(n *bs.Node) {
+	c.hidden = make([]float64, c.Size)
+	c.cellState = make([]float64, c.Size)
+	c.steps = nil
+	c.carryHidden, c.carryCell = nil, nil
+}
@@ -11,6 +11,10 @@ type convConstructor struct {
 	dims      []int
 	inputDims []int
 	filter    []int
+
+	// whether or not there is one group for each input channel, which is resolved into NumGroups
+	// once the input dimensions are known
+	depthwise bool
 }
 
 type conv struct {
@@ -30,6 +34,13 @@ type conv struct {
 	Padding      []int
 	PaddingValue float64
 
+	// the spacing between the inputs given to each point of the filter
+	Dilations []int
+
+	// the number of groups that the channels (the last dimension of the inputs) are split into.
+	// Each group has its own parameters, even with ParamSharing.
+	NumGroups int
+
 	// always either 0 or 1. It is represented as an integer to make the math easier and to reduce
 	// the number of necessary conditionals
 	NumBiases int
@@ -38,7 +49,8 @@ type conv struct {
 	Bias float64
 
 	// weights are stored by the output value they correspond to, with the smaller filter indexes
-	// within. Biases are appended to the end.
+	// within. Biases are appended to the end. With ParamSharing, there is instead one filter for
+	// each group within each depth.
 	Ws []float64
 }
 
@@ -112,7 +124,7 @@ func (c *conv) Filter(dims ...int) *conv {
 // filters. Stride will panic if called after the Operator has been Finalized.
 //
 // At finalization, the convolutional Operator will return error if any dimensions of Stride are
-// larger than Filter.
+// larger than the region covered by Filter (including Dilation).
 //
 // Stride is optional.
 func (c *conv) Stride(dims ...int) *conv {
@@ -201,6 +213,52 @@ func (c *conv) BiasValue(b float64) *conv {
 	return c
 }
 
+// Dilation sets the spacing between the inputs given to each point of the filter, in each
+// dimension, for atrous convolutions. A dilation of 1 is the same as none; a dilation of 2 skips
+// every other input. The region covered by the filter in each dimension is then:
+// (Filter - 1) * Dilation + 1, which is used in place of Filter for the output dimensions.
+// Dilation defaults to 1 in all dimensions. Dilation will panic if called after the Operator has
+// been Finalized.
+func (c *conv) Dilation(dims ...int) *conv {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.Dilations = dims
+	return c
+}
+
+// Groups splits the channels of the input into 'n' groups, which are convolved separately, each
+// with their own parameters. The channels are given by the last dimension of InputDims, which must
+// be divisible by n. The filter must cover exactly one group: the last dimension of Filter and of
+// Stride must both be equal to (channels / n), without Padding or Dilation. The outputs then have
+// one value for each group in their last dimension (before Depth), so that each depth has the
+// output of every group.
+//
+// Groups defaults to 1. It is most useful with ParamSharing; without it, every output already has
+// its own parameters. Groups will panic if called after the Operator has been Finalized.
+func (c *conv) Groups(n int) *conv {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.NumGroups, c.depthwise = n, false
+	return c
+}
+
+// Depthwise sets the number of groups equal to the number of channels, so that each channel is
+// convolved separately, as with depthwise-separable convolutions. The last dimension of Filter
+// and of Stride must therefore be 1. See Groups for more information. Depthwise will panic if
+// called after the Operator has been Finalized.
+func (c *conv) Depthwise() *conv {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.depthwise = true
+	return c
+}
+
 // ***************************************************
 // Helper Functions
 // ***************************************************
@@ -228,8 +286,8 @@ func (c *conv) Size() (int, error) {
 		return 0, errors.Errorf("Filter has not been set")
 	}
 
-	dimList := [][]int{c.inputDims, c.dims, c.filter, c.Padding}
-	names := []string{"InputDims", "Dims", "Filter", "Stride", "Padding"} // only used in case of error
+	dimList := [][]int{c.inputDims, c.dims, c.filter, c.Str, c.Dilations}
+	names := []string{"InputDims", "Dims", "Filter", "Stride", "Dilation"} // only used in case of error
 	for i := range dimList {
 		if dimList[i] == nil {
 			continue
@@ -248,16 +306,41 @@ func (c *conv) Size() (int, error) {
 		}
 	}
 
+	if c.Padding != nil {
+		if len(c.Padding) != len(c.inputDims) {
+			return 0, errors.Errorf("Padding has different length to InputDims (%d != %d)", len(c.Padding), len(c.inputDims))
+		}
+
+		for d := range c.Padding {
+			if c.Padding[d] < 0 {
+				return 0, errors.Errorf("Padding[%d] = %d, must be ≥ 0", d, c.Padding[d])
+			}
+		}
+	}
+
 	if c.Dep < 1 {
-		return 0, errors.Errorf("Depth is < 1 (%d)")
+		return 0, errors.Errorf("Depth is < 1 (%d)", c.Dep)
+	}
+
+	if c.Dilations == nil {
+		c.Dilations = make([]int, len(c.inputDims))
+		for d := range c.Dilations {
+			c.Dilations[d] = 1
+		}
+	}
+
+	// the size of the region covered by the filter, with dilation
+	extent := make([]int, len(c.inputDims))
+	for d := range extent {
+		extent[d] = (c.filter[d]-1)*c.Dilations[d] + 1
 	}
 
 	if c.Str == nil {
 		c.Str = c.filter
 	} else {
 		for d := range c.Str {
-			if c.Str[d] > c.filter[d] {
-				return 0, errors.Errorf("Filter[%d] is less than than Stride[%d] (%d > %d)", d, d, c.filter[d], c.Str[d])
+			if c.Str[d] > extent[d] {
+				return 0, errors.Errorf("Filter[%d] (with dilation) is less than than Stride[%d] (%d < %d)", d, d, extent[d], c.Str[d])
 			}
 		}
 	}
@@ -266,11 +349,15 @@ func (c *conv) Size() (int, error) {
 		c.Padding = make([]int, len(c.inputDims))
 	}
 
+	if err := c.setGroups(); err != nil {
+		return 0, err
+	}
+
 	if c.dims != nil { // if everything is filled in, check whether or not it works
 		for i := range c.inputDims {
 			in := c.inputDims[i] + 2*c.Padding[i]
 			d := c.dims[i]
-			f := c.filter[i]
+			f := extent[i]
 			s := c.Str[i]
 
 			if (in+s-f)%s != 0 {
@@ -286,7 +373,7 @@ func (c *conv) Size() (int, error) {
 
 		for i := range c.inputDims {
 			in := c.inputDims[i] + 2*c.Padding[i]
-			f := c.filter[i]
+			f := extent[i]
 			s := c.Str[i]
 
 			if (in+s-f)%s != 0 {
@@ -307,6 +394,35 @@ func (c *conv) Size() (int, error) {
 	return c.Outs.Size() * c.Dep, nil
 }
 
+// setGroups resolves the number of groups, and checks that the filter covers exactly one group
+func (c *conv) setGroups() error {
+	last := len(c.inputDims) - 1
+	channels := c.inputDims[last]
+
+	if c.depthwise {
+		c.NumGroups = channels
+	} else if c.NumGroups == 0 {
+		c.NumGroups = 1
+	}
+
+	if c.NumGroups < 1 {
+		return errors.Errorf("Groups is < 1 (%d)", c.NumGroups)
+	} else if c.NumGroups == 1 {
+		return nil
+	} else if channels%c.NumGroups != 0 {
+		return errors.Errorf("Number of channels (the last input dimension) is not divisible by Groups (%d %% %d != 0)", channels, c.NumGroups)
+	}
+
+	size := channels / c.NumGroups
+	if c.filter[last] != size || c.Str[last] != size {
+		return errors.Errorf("The last dimension of Filter and Stride must equal the size of each group (%d, %d != %d)", c.filter[last], c.Str[last], size)
+	} else if c.Padding[last] != 0 || c.Dilations[last] != 1 {
+		return errors.Errorf("The last dimension cannot have Padding or Dilation with Groups")
+	}
+
+	return nil
+}
+
 // returns whether or not the point in the inputs (set of inputs plus padding) is outside the
 // boundaries of the actual inputs
 func (c *conv) isPadding(point []int) bool {
@@ -316,7 +432,7 @@ func (c *conv) isPadding(point []int) bool {
 
 	// if any are less than 0, it's padding
 	for i := range p {
-		if p[i] < 0 || p[i] >= c.Outs.Dim(i) {
+		if p[i] < 0 || p[i] >= c.Ins.Dim(i) {
 			return true
 		}
 	}
@@ -324,14 +440,31 @@ func (c *conv) isPadding(point []int) bool {
 	return false
 }
 
+// inputPoint returns the point in the inputs (including padding) given to the point in the filter
+// for the output point
+func (c *conv) inputPoint(out_p, mod []int) []int {
+	in_p := make([]int, len(out_p))
+	for i := range in_p {
+		in_p[i] = out_p[i]*c.Str[i] + mod[i]*c.Dilations[i]
+	}
+
+	return in_p
+}
+
+// group returns the group that the output point belongs to
+func (c *conv) group(out_p []int) int {
+	if c.NumGroups <= 1 {
+		return 0
+	}
+
+	return out_p[len(out_p)-1]
+}
+
 // out_p does not include depth
 func (c *conv) inputsTo(out_p []int) []int {
 	// here, underscores are used as a suffix to indicate the type of the variable. For example,
 	// x_i would be an index with name 'x', and x_p would be an n-dimensional point with name 'x'.
 
-	// topleft is the top-left base point for the filter, including padding
-	topLeft_p := mapMult(out_p, c.Str)
-
 	// the list of input indexes to be supplied to c
 	inList := make([]int, c.Filt.Size())
 
@@ -340,12 +473,7 @@ func (c *conv) inputsTo(out_p []int) []int {
 	for i := 0; i < c.Filt.Size(); i++ {
 
 		// in is the current input that we're looking at, including padding
-		var in_p []int
-		{
-			in_p = make([]int, len(topLeft_p))
-			copy(in_p, topLeft_p)
-			mapAdd(in_p, fMod)
-		}
+		in_p := c.inputPoint(out_p, fMod)
 
 		if c.isPadding(in_p) {
 			// -1 indicates that it is out of range of the inputs -- it's padding
@@ -367,7 +495,7 @@ func (c *conv) inputsTo(out_p []int) []int {
 func (c *conv) weight(out, mod []int, depth int) float64 {
 	filterSize := c.Filt.Size() + c.NumBiases
 	if c.ShareParams {
-		return c.Ws[depth*filterSize+c.Filt.Index(mod)]
+		return c.Ws[(depth*c.NumGroups+c.group(out))*filterSize+c.Filt.Index(mod)]
 	} else {
 		return c.Ws[depth*c.Outs.Size()*filterSize+
 			c.Outs.Index(out)*filterSize+
@@ -379,7 +507,7 @@ func (c *conv) weight(out, mod []int, depth int) float64 {
 func (c *conv) bias(out []int, depth int) float64 {
 	filterSize := c.Filt.Size() + c.NumBiases
 	if c.ShareParams {
-		return c.Ws[depth*filterSize+c.Filt.Size()]
+		return c.Ws[(depth*c.NumGroups+c.group(out))*filterSize+c.Filt.Size()]
 	} else {
 		return c.Ws[depth*c.Outs.Size()*filterSize+
 			c.Outs.Index(out)*filterSize+
@@ -401,6 +529,17 @@ func (t *conv) Finalize(n *bs.Node) error {
 		return err
 	}
 
+	// Operators saved before Dilation and Groups existed will not have them
+	if t.Dilations == nil {
+		t.Dilations = make([]int, len(t.Str))
+		for d := range t.Dilations {
+			t.Dilations[d] = 1
+		}
+	}
+	if t.NumGroups == 0 {
+		t.NumGroups = 1
+	}
+
 	if t.Ins.Size() != n.NumInputs() {
 		return errors.Errorf("Mismatch between expected number of inputs and actual (%d != %d)", t.Ins.Size(), n.NumInputs())
 	}
@@ -409,7 +548,7 @@ func (t *conv) Finalize(n *bs.Node) error {
 	if !t.ShareParams {
 		wLen = (t.Filt.Size() + t.NumBiases) * size
 	} else {
-		wLen = (t.Filt.Size() + t.NumBiases) * t.Dep
+		wLen = (t.Filt.Size() + t.NumBiases) * t.Dep * t.NumGroups
 	}
 
 	if t.Ws == nil {
@@ -435,7 +574,12 @@ func (t *conv) OutputShape(ins []*bs.Node) (tensors.Tensor, error) {
 		return tensors.Tensor{}, err
 	}
 
-	return tensors.NewTensor(t.Outs.Dims), nil
+	dims := append([]int{}, t.Outs.Dims...)
+	if t.Dep > 1 {
+		dims = append(dims, t.Dep)
+	}
+
+	return tensors.NewTensor(dims), nil
 }
 
 func (t *conv) Evaluate(n *bs.Node, values []float64) {
@@ -497,20 +641,44 @@ func (t *conv) Grad(n *bs.Node, index int) float64 {
 	mod := index % filterSize
 	index /= filterSize
 
+	if t.ShareParams {
+		return t.sharedGrad(n, mod, index/t.NumGroups, index%t.NumGroups)
+	}
+
 	out := index % t.Outs.Size()
 	depth := index / t.Outs.Size()
 
+	return t.gradAt(n, mod, out, depth)
+}
+
+// gradAt returns the gradient of the weight at the point in the filter given by mod (or the bias)
+// from the output at the given index and depth
+func (t *conv) gradAt(n *bs.Node, mod, out, depth int) float64 {
+	delta := n.Delta(out + depth*t.Outs.Size())
+
 	if mod == t.Filt.Size() { // if it's a bias
-		return t.Bias * n.Delta(out)
+		return t.Bias * delta
+	}
+
+	in_p := t.inputPoint(t.Outs.Point(out), t.Filt.Point(mod))
+	if t.isPadding(in_p) {
+		return t.PaddingValue * delta
 	} else {
-		in_p := mapAdd(mapMult(t.Outs.Point(out), t.Str), t.Filt.Point(mod))
+		return n.InputValue(t.Ins.Index(mapSub(in_p, t.Padding))) * delta
+	}
+}
 
-		if t.isPadding(in_p) {
-			return t.PaddingValue * n.Delta(out+depth*t.Outs.Size())
-		} else {
-			return n.InputValue(t.Ins.Index(mapSub(in_p, t.Padding))) * n.Delta(out+depth*t.Outs.Size())
+// sharedGrad returns the gradient of a shared weight, which is the sum from every output in its
+// depth and group
+func (t *conv) sharedGrad(n *bs.Node, mod, depth, group int) float64 {
+	var sum float64
+	for out := 0; out < t.Outs.Size(); out++ {
+		if t.group(t.Outs.Point(out)) == group {
+			sum += t.gradAt(n, mod, out, depth)
 		}
 	}
+
+	return sum
 }
 
 func (t *conv) Weights() []float64 {
@@ -0,0 +1,406 @@
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"github.com/sharnoff/tensors"
+)
+
+type convTranspose struct {
+	*convConstructor
+
+	Outs *utils.MultiDim
+	Ins  *utils.MultiDim
+	// Filt short for Filter
+	Filt *utils.MultiDim
+
+	// Dep short for depth
+	Dep int
+
+	// Str is short for stride
+	Str     []int
+	Padding []int
+
+	// always either 0 or 1. It is represented as an integer to make the math easier and to reduce
+	// the number of necessary conditionals
+	NumBiases int
+
+	// the value multiplied by bias
+	Bias float64
+
+	// weights are stored by depth, each with the filter followed by the bias. Unlike Conv, the
+	// weights are always shared across all positions.
+	Ws []float64
+}
+
+// ConvTranspose returns a transposed (or fractionally strided) convolution with weights, available
+// for any number of dimensions, which implements badstudent.Operator. It is the reverse of Conv:
+// each input value is multiplied by the filter and added to the region of the outputs that it
+// covers, so that the outputs are larger than the inputs. This makes it suitable for decoders,
+// such as in autoencoders and segmentation.
+//
+// Each output dimension is given by: (InputDim - 1) * Stride + Filter - 2 * Padding, where the
+// padding is removed from both ends of the outputs.
+//
+// As with Conv, ConvTranspose does not return a completed Operator; the methods InputDims and
+// Filter must be called before it can be Finalized. The other methods are optional, and have the
+// same meaning as they do for Conv.
+func ConvTranspose() *convTranspose {
+	c := new(convTranspose)
+	c.Dep = default_depth
+	c.Bias = defaultValue["conv-bias"]
+	c.NumBiases = default_numBiases
+	c.convConstructor = new(convConstructor)
+	return c
+}
+
+// ***************************************************
+// Customization functions
+// ***************************************************
+
+// Dims sets the output dimensions of the transposed convolution, not including depth. Dims is
+// optional, as it can be calculated from other information, but it will be checked if it is
+// provided. Dims will panic if called after the Operator has been finalized.
+func (c *convTranspose) Dims(dims ...int) *convTranspose {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.dims = dims
+	return c
+}
+
+// InputDims sets the input dimensions of the transposed convolution. This is REQUIRED unless the
+// Operator is being loaded. InputDims will panic if called after the Operator has been Finalized.
+func (c *convTranspose) InputDims(dims ...int) *convTranspose {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.inputDims = dims
+	return c
+}
+
+// Filter sets the size of the filter in each dimension. This is REQUIRED unless the Operator is
+// being loaded. Filter will panic if called after the Operator has been Finalized.
+func (c *convTranspose) Filter(dims ...int) *convTranspose {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.filter = dims
+	return c
+}
+
+// Stride sets the space between the regions of the outputs that each input is given to. Stride
+// defaults to the same size as the filters. Stride will panic if called after the Operator has
+// been Finalized.
+func (c *convTranspose) Stride(dims ...int) *convTranspose {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.Str = dims
+	return c
+}
+
+// Pad sets the amount that is removed from both ends of the outputs in each dimension. Pad
+// defaults to none. Pad will panic if called after the Operator has been Finalized.
+func (c *convTranspose) Pad(dims ...int) *convTranspose {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.Padding = dims
+	return c
+}
+
+// Depth sets the number of 'copies' of the output that are going to be made, each with a
+// different filter. If Depth > 1, the outputs will have an appended dimension of value equal to
+// Depth. Depth defaults to 1, and will cause Finalize to error if less than 1.
+func (c *convTranspose) Depth(d int) *convTranspose {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.Dep = d
+	return c
+}
+
+// WithBiases adds a bias to each filter. Transposed convolutions default to having biases.
+func (c *convTranspose) WithBiases() *convTranspose {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.NumBiases = 1
+	return c
+}
+
+// NoBiases removes the biases from each filter. Transposed convolutions default to having biases.
+func (c *convTranspose) NoBiases() *convTranspose {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.NumBiases = 0
+	return c
+}
+
+// BiasValue sets the value multiplied by the biases. The default value can be set by
+// SetDefault("conv-bias")
+func (c *convTranspose) BiasValue(b float64) *convTranspose {
+	if c.convConstructor == nil {
+		panic("convolutional Operator has already been finalized")
+	}
+
+	c.Bias = b
+	return c
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+// MustSize calls Size, but panics if it encounters an error.
+func (c *convTranspose) MustSize() int {
+	size, err := c.Size()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return size
+}
+
+// Size returns the expected size of the transposed convolution, before it has been finalized. If
+// the configuration is invalid, it will return error, just as Finalize would.
+func (c *convTranspose) Size() (int, error) {
+	if c.Outs != nil {
+		return c.Outs.Size() * c.Dep, nil
+	}
+
+	if c.inputDims == nil {
+		return 0, errors.Errorf("InputDims has not been set")
+	} else if c.filter == nil {
+		return 0, errors.Errorf("Filter has not been set")
+	}
+
+	dimList := [][]int{c.inputDims, c.dims, c.filter, c.Str}
+	names := []string{"InputDims", "Dims", "Filter", "Stride"} // only used in case of error
+	for i := range dimList {
+		if dimList[i] == nil {
+			continue
+		}
+
+		if len(dimList[i]) == 0 {
+			return 0, errors.Errorf("%s has been set with length zero", names[i])
+		} else if len(dimList[i]) != len(c.inputDims) {
+			return 0, errors.Errorf("%s has different length to InputDims (%d != %d)", names[i], len(dimList[i]), len(c.inputDims))
+		}
+
+		for d := range dimList[i] {
+			if dimList[i][d] < 1 {
+				return 0, errors.Errorf("%s[%d] = %d, must be ≥ 1", names[i], d, dimList[i][d])
+			}
+		}
+	}
+
+	if c.Dep < 1 {
+		return 0, errors.Errorf("Depth is < 1 (%d)", c.Dep)
+	}
+
+	if c.Str == nil {
+		c.Str = c.filter
+	}
+
+	if c.Padding == nil {
+		c.Padding = make([]int, len(c.inputDims))
+	} else if len(c.Padding) != len(c.inputDims) {
+		return 0, errors.Errorf("Padding has different length to InputDims (%d != %d)", len(c.Padding), len(c.inputDims))
+	}
+
+	dims := make([]int, len(c.inputDims))
+	for i := range dims {
+		dims[i] = (c.inputDims[i]-1)*c.Str[i] + c.filter[i] - 2*c.Padding[i]
+
+		if c.Padding[i] < 0 || dims[i] < 1 {
+			return 0, errors.Errorf("Padding[%d] is invalid (%d); it must be ≥ 0 and leave at least one output", i, c.Padding[i])
+		} else if c.dims != nil && c.dims[i] != dims[i] {
+			return 0, errors.Errorf("Dimension #%d does not produce desired output (InputDim - 1) * Stride + Filter - 2*Padding != OutputDim "+
+				"((%d - 1) * %d + %d - 2*%d != %d)", i, c.inputDims[i], c.Str[i], c.filter[i], c.Padding[i], c.dims[i])
+		}
+	}
+
+	c.Outs = utils.NewMultiDim(dims)
+	c.Ins = utils.NewMultiDim(c.inputDims)
+	c.Filt = utils.NewMultiDim(c.filter)
+
+	c.convConstructor = nil
+
+	return c.Outs.Size() * c.Dep, nil
+}
+
+// inputFor returns the index of the input that is multiplied by the given point in the filter to
+// give to the output at out_p, or -1 if there is none.
+func (c *convTranspose) inputFor(out_p, filt_p []int) int {
+	in_p := make([]int, len(out_p))
+	for i := range in_p {
+		p := out_p[i] + c.Padding[i] - filt_p[i]
+		if p < 0 || p%c.Str[i] != 0 || p/c.Str[i] >= c.Ins.Dim(i) {
+			return -1
+		}
+
+		in_p[i] = p / c.Str[i]
+	}
+
+	return c.Ins.Index(in_p)
+}
+
+// outputFor returns the index of the output (without depth) that the input at in_p gives to at the
+// given point in the filter, or -1 if it has been removed by padding.
+func (c *convTranspose) outputFor(in_p, filt_p []int) int {
+	out_p := make([]int, len(in_p))
+	for i := range out_p {
+		out_p[i] = in_p[i]*c.Str[i] + filt_p[i] - c.Padding[i]
+		if out_p[i] < 0 || out_p[i] >= c.Outs.Dim(i) {
+			return -1
+		}
+	}
+
+	return c.Outs.Index(out_p)
+}
+
+// ***************************************************
+// Interface implementation
+// ***************************************************
+
+func (t *convTranspose) TypeString() string {
+	return "conv-transpose"
+}
+
+func (t *convTranspose) Finalize(n *bs.Node) error {
+	if _, err := t.Size(); err != nil {
+		return err
+	}
+
+	if t.Ins.Size() != n.NumInputs() {
+		return errors.Errorf("Mismatch between expected number of inputs and actual (%d != %d)", t.Ins.Size(), n.NumInputs())
+	}
+
+	wLen := (t.Filt.Size() + t.NumBiases) * t.Dep
+	if t.Ws == nil {
+		t.Ws = make([]float64, wLen)
+	} else if len(t.Ws) != wLen {
+		return errors.Errorf("Number of saved weights not equal to expected number (%d != %d)", len(t.Ws), wLen)
+	}
+
+	return nil
+}
+
+func (t *convTranspose) Get() interface{} {
+	return *t
+}
+
+func (t *convTranspose) Blank() interface{} {
+	return t
+}
+
+func (t *convTranspose) OutputShape(ins []*bs.Node) (tensors.Tensor, error) {
+	if _, err := t.Size(); err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	dims := append([]int{}, t.Outs.Dims...)
+	if t.Dep > 1 {
+		dims = append(dims, t.Dep)
+	}
+
+	return tensors.NewTensor(dims), nil
+}
+
+func (t *convTranspose) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+	filterSize := t.Filt.Size() + t.NumBiases
+
+	f := func(v int) {
+		depth := v / t.Outs.Size()
+		ws := t.Ws[depth*filterSize : (depth+1)*filterSize]
+
+		out_p := t.Outs.Point(v % t.Outs.Size())
+		filt_p := make([]int, len(t.Str))
+
+		var sum float64
+		for i := 0; i < t.Filt.Size(); i++ {
+			if in := t.inputFor(out_p, filt_p); in != -1 {
+				sum += inputs[in] * ws[i]
+			}
+
+			t.Filt.Increment(filt_p)
+		}
+
+		if t.NumBiases != 0 {
+			sum += t.Bias * ws[t.Filt.Size()]
+		}
+
+		values[v] = sum
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+}
+
+func (t *convTranspose) InputDeltas(n *bs.Node) []float64 {
+	ds := make([]float64, n.NumInputs())
+	filterSize := t.Filt.Size() + t.NumBiases
+
+	f := func(in int) {
+		in_p := t.Ins.Point(in)
+		filt_p := make([]int, len(t.Str))
+
+		for i := 0; i < t.Filt.Size(); i++ {
+			if out := t.outputFor(in_p, filt_p); out != -1 {
+				for d := 0; d < t.Dep; d++ {
+					ds[in] += n.Delta(d*t.Outs.Size()+out) * t.Ws[d*filterSize+i]
+				}
+			}
+
+			t.Filt.Increment(filt_p)
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(ds), f, opsPerThread, threadsPerCPU)
+
+	return ds
+}
+
+func (t *convTranspose) Grad(n *bs.Node, index int) float64 {
+	filterSize := t.Filt.Size() + t.NumBiases
+
+	mod := index % filterSize
+	depth := index / filterSize
+	start := depth * t.Outs.Size()
+
+	var sum float64
+	if mod == t.Filt.Size() { // if it's a bias
+		for out := 0; out < t.Outs.Size(); out++ {
+			sum += n.Delta(start + out)
+		}
+
+		return t.Bias * sum
+	}
+
+	filt_p := t.Filt.Point(mod)
+	for in := 0; in < t.Ins.Size(); in++ {
+		if out := t.outputFor(t.Ins.Point(in), filt_p); out != -1 {
+			sum += n.InputValue(in) * n.Delta(start+out)
+		}
+	}
+
+	return sum
+}
+
+func (t *convTranspose) Weights() []float64 {
+	return t.Ws
+}
@@ -0,0 +1,215 @@
+// dropout.go contains the Operators that randomly drop values during training:
+// * Dropout
+// * Alpha Dropout
+//
+// Both can be made spatial (dropping whole channels) or variational (keeping the same values
+// dropped for the whole of each sequence in a recurrent Network).
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/tensors"
+	"math"
+	"math/rand"
+)
+
+// the value that dropped values are set to by AlphaDropout, before the affine transformation. It
+// is equal to -λα from SELU
+const alphaDropValue float64 = -1.7580993408473766
+
+type dropout struct {
+	// the probability that each value (or channel) is dropped
+	P float64
+
+	// whether or not it is alpha dropout
+	Alpha bool
+
+	// whether or not whole channels (the last dimension) are dropped together
+	PerChannel bool
+
+	// whether or not the mask is kept for the whole of each sequence
+	Fixed bool
+
+	// the number of values in each channel; 1 if not PerChannel
+	group int
+
+	// keep is the mask for the current evaluation, with one value for each channel. It is nil
+	// outside of training
+	keep []bool
+
+	// masks stores the masks from each time step of the current sequence in a recurrent Network, so
+	// that they can be used again while backpropagating.
+	masks [][]bool
+}
+
+// Dropout returns a dropout Layer, which implements badstudent.Operator. During training, each
+// value is set to zero with probability p, and the rest are divided by 1 - p so that the expected
+// sum is unchanged. A new set of values is dropped for each iteration. During testing and
+// GetOutputs, values are passed through unchanged.
+//
+// Whole channels can be dropped instead with Spatial, and the same values can be dropped for
+// whole sequences with Variational.
+//
+// Finalize will return error if p is not within the range [0, 1).
+func Dropout(p float64) *dropout {
+	return &dropout{P: p}
+}
+
+// AlphaDropout returns a dropout Layer for use with SELU, which implements badstudent.Operator.
+// Instead of being set to zero, dropped values are set to the negative saturation value of SELU,
+// and all values are then scaled and shifted so that the mean and variance of the outputs are
+// preserved. Otherwise, it is the same as Dropout.
+//
+// For more information, see: https://arxiv.org/abs/1706.02515
+func AlphaDropout(p float64) *dropout {
+	return &dropout{P: p, Alpha: true}
+}
+
+// ***************************************************
+// Customization Functions
+// ***************************************************
+
+// Spatial changes the dropout to drop whole channels, taking the last dimension of the values as
+// the channels, as with the outputs of Conv with Depth.
+func (d *dropout) Spatial() *dropout {
+	d.PerChannel = true
+	return d
+}
+
+// Variational changes the dropout so that, in recurrent Networks, the same values are dropped for
+// every time step of a sequence. A new set is chosen once each sequence has finished, as given by
+// Sequential.SetEnded. Variational has no effect outside of recurrent Networks.
+//
+// For more information, see: https://arxiv.org/abs/1512.05287
+func (d *dropout) Variational() *dropout {
+	d.Fixed = true
+	return d
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+// sample returns a new mask
+func (d *dropout) sample(size int) []bool {
+	keep := make([]bool, size/d.group)
+	for c := range keep {
+		keep[c] = rand.Float64() >= d.P
+	}
+
+	return keep
+}
+
+// affine returns the factor and offset applied to values after dropout, given by: a*x + b.
+func (d *dropout) affine() (float64, float64) {
+	if !d.Alpha {
+		return 1 / (1 - d.P), 0
+	}
+
+	a := 1 / math.Sqrt((1-d.P)*(1+d.P*alphaDropValue*alphaDropValue))
+	return a, -a * alphaDropValue * d.P
+}
+
+// ***************************************************
+// Interface-required functions
+// ***************************************************
+
+func (d *dropout) TypeString() string {
+	if d.Alpha {
+		return "alpha-dropout"
+	}
+
+	return "dropout"
+}
+
+func (d *dropout) Finalize(n *bs.Node) error {
+	if d.P < 0 || d.P >= 1 {
+		return errors.Errorf("Probability must be within the range [0, 1) (%v)", d.P)
+	}
+
+	d.group = 1
+	if d.PerChannel {
+		dims := n.Dims()
+		d.group = n.Size() / dims[len(dims)-1]
+	}
+
+	return nil
+}
+
+func (d *dropout) Get() interface{} {
+	return *d
+}
+
+func (d *dropout) Blank() interface{} {
+	return d
+}
+
+func (d *dropout) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	ls := make([]tensors.Tensor, len(inputs))
+	for i := range ls {
+		ls[i] = inputs[i].Shape()
+	}
+
+	return bs.ConcatShape(ls)
+}
+
+func (d *dropout) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	if !n.Training() {
+		copy(values, inputs)
+		return
+	}
+
+	fixed := d.Fixed && n.Recurrent()
+
+	if n.Replaying() {
+		// the masks are used in the reverse order that they were made. With a fixed mask, it
+		// has not changed.
+		if !fixed {
+			d.keep = d.masks[len(d.masks)-1]
+			d.masks = d.masks[:len(d.masks)-1]
+		}
+	} else if !fixed || d.keep == nil {
+		d.keep = d.sample(len(values))
+		if n.Recurrent() && !fixed {
+			d.masks = append(d.masks, d.keep)
+		}
+	}
+
+	a, b := d.affine()
+	for i := range values {
+		v := inputs[i]
+		if !d.keep[i/d.group] {
+			if d.Alpha {
+				v = alphaDropValue
+			} else {
+				v = 0
+			}
+		}
+
+		values[i] = a*v + b
+	}
+}
+
+func (d *dropout) InputDeltas(n *bs.Node) []float64 {
+	ds := make([]float64, n.NumInputs())
+
+	// InputDeltas is only called during training, so keep will be set
+	a, _ := d.affine()
+	for i := range ds {
+		if d.keep[i/d.group] {
+			ds[i] = a * n.Delta(i)
+		}
+	}
+
+	return ds
+}
+
+// EndSequence discards the masks from the sequence that has just finished, so that a new one will
+// be chosen for the next.
+func (d *dropout) EndSequence(n *bs.Node) {
+	d.keep = nil
+	d.masks = nil
+}
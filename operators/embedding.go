@@ -0,0 +1,183 @@
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"github.com/sharnoff/tensors"
+	"math"
+	"sync/atomic"
+)
+
+type embedding struct {
+	Vocab int
+	Dim   int
+
+	// the table of vectors, stored by row: the vector for index i is Ws[i*Dim : (i+1)*Dim].
+	Ws []float64
+
+	// the number of input values that have been outside of the range [0, Vocab), or NaN. Accessed
+	// atomically
+	outOfRange int64
+
+	// the inputs that used each row of the table at the most recent evaluation, and the rows in the
+	// order they were first used. Inputs that were out of range are not included.
+	rows  map[int][]int
+	order []int
+}
+
+// Embedding returns an embedding Layer, which implements badstudent.Adjustable and
+// badstudent.Sparse. Each of its input values is interpreted as an integer index into a table of
+// 'vocab' vectors of length 'dim', and the Node outputs the vector for each index. With n input
+// values, the output has dimensions [n, dim], so that the last dimension is the channels (as with
+// the outputs of Conv).
+//
+// Input values are rounded to the nearest integer. Any that are outside the range [0, vocab) are
+// clamped to it, and NaN is treated as zero; the number of these is recorded, and is given by
+// OutOfRange. These values are not used to train the vectors they are clamped to. Embedding does
+// not give any deltas to its inputs.
+//
+// Only the vectors for the current indexes have non-zero gradients, so Optimizers only need to
+// visit those.
+func Embedding(vocab, dim int) *embedding {
+	return &embedding{Vocab: vocab, Dim: dim}
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+// index returns the index into the table given by the input value at 'in', clamped to the range
+// [0, Vocab), along with whether or not the value was already within that range
+func (e *embedding) index(n *bs.Node, in int) (int, bool) {
+	v := n.InputValue(in)
+	if math.IsNaN(v) {
+		return 0, false
+	}
+
+	// the value is rounded after being clamped so that infinite values can be converted
+	v = math.Round(math.Max(-1, math.Min(float64(e.Vocab), v)))
+	if v < 0 {
+		return 0, false
+	} else if int(v) >= e.Vocab {
+		return e.Vocab - 1, false
+	}
+
+	return int(v), true
+}
+
+// OutOfRange returns the number of input values given to the Layer that have been outside of the
+// range [0, vocab), or NaN, and so have been clamped.
+func (e *embedding) OutOfRange() int {
+	return int(atomic.LoadInt64(&e.outOfRange))
+}
+
+// ***************************************************
+// Interface-required functions
+// ***************************************************
+
+func (e *embedding) TypeString() string {
+	return "embedding"
+}
+
+func (e *embedding) Finalize(n *bs.Node) error {
+	// if it's been loaded from a file...
+	if len(e.Ws) != 0 {
+		return nil
+	}
+
+	if e.Vocab < 1 {
+		return errors.Errorf("Vocabulary size must be ≥ 1 (%d)", e.Vocab)
+	} else if e.Dim < 1 {
+		return errors.Errorf("Dimension must be ≥ 1 (%d)", e.Dim)
+	}
+
+	e.Ws = make([]float64, e.Vocab*e.Dim)
+	return nil
+}
+
+func (e *embedding) Get() interface{} {
+	return *e
+}
+
+func (e *embedding) Blank() interface{} {
+	return e
+}
+
+func (e *embedding) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	size := 0
+	for _, in := range inputs {
+		size += in.Size()
+	}
+
+	return tensors.NewTensor([]int{size, e.Dim}), nil
+}
+
+func (e *embedding) Evaluate(n *bs.Node, values []float64) {
+	num := n.NumInputs()
+	idx, valid := make([]int, num), make([]bool, num)
+
+	f := func(in int) {
+		i, ok := e.index(n, in)
+		if !ok {
+			atomic.AddInt64(&e.outOfRange, 1)
+		}
+
+		idx[in], valid[in] = i, ok
+
+		row := e.Ws[i*e.Dim:]
+		for d := 0; d < e.Dim; d++ {
+			values[d*num+in] = row[d]
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 10, 1
+	utils.MultiThread(0, num, f, opsPerThread, threadsPerCPU)
+
+	// the inputs that use each row are kept so that Grad and Touched do not need to go through
+	// every input
+	e.rows, e.order = make(map[int][]int), nil
+	for in, i := range idx {
+		if !valid[in] {
+			continue
+		}
+
+		if _, ok := e.rows[i]; !ok {
+			e.order = append(e.order, i)
+		}
+
+		e.rows[i] = append(e.rows[i], in)
+	}
+}
+
+// The input values are indexes, so they do not have deltas.
+func (e *embedding) InputDeltas(n *bs.Node) []float64 {
+	return make([]float64, n.NumInputs())
+}
+
+func (e *embedding) Grad(n *bs.Node, index int) float64 {
+	row, d := index/e.Dim, index%e.Dim
+	num := n.NumInputs()
+
+	var sum float64
+	for _, in := range e.rows[row] {
+		sum += n.Delta(d*num + in)
+	}
+
+	return sum
+}
+
+func (e *embedding) Weights() []float64 {
+	return e.Ws
+}
+
+func (e *embedding) Touched(n *bs.Node) []int {
+	idx := make([]int, 0, len(e.order)*e.Dim)
+	for _, row := range e.order {
+		for d := 0; d < e.Dim; d++ {
+			idx = append(idx, row*e.Dim+d)
+		}
+	}
+
+	return idx
+}
@@ -0,0 +1,280 @@
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"github.com/sharnoff/tensors"
+	"math"
+)
+
+// splitter divides a single dimension of the input into equal parts, for Operators that combine
+// the values at the same position in each part
+type splitter struct {
+	// the dimension that is split, which may be negative to count from the end
+	Dim int
+
+	// the dimension that is split, with negative values resolved, and the dimensions of the inputs
+	// and of each part. Set by Finalize
+	dim       int
+	ins, outs *utils.MultiDim
+}
+
+// setDims sets the dimensions of the inputs and of each of the given number of parts, returning
+// error if the input cannot be split
+func (s *splitter) setDims(name string, inputs []*bs.Node, parts int) error {
+	dims, err := inputDims(name, inputs)
+	if err != nil {
+		return err
+	}
+
+	s.dim = s.Dim
+	if s.dim < 0 {
+		s.dim += len(dims)
+	}
+
+	if s.dim < 0 || s.dim >= len(dims) {
+		return errors.Errorf("Dimension to split is out of range (%d for %d dimensions)", s.Dim, len(dims))
+	} else if dims[s.dim]%parts != 0 {
+		return errors.Errorf("Dimension %d cannot be split into %d parts (%d %% %d != 0)", s.dim, parts, dims[s.dim], parts)
+	}
+
+	outs := make([]int, len(dims))
+	copy(outs, dims)
+	outs[s.dim] /= parts
+
+	s.ins, s.outs = utils.NewMultiDim(dims), utils.NewMultiDim(outs)
+	return nil
+}
+
+// inputsTo returns the index of the input in each part that is given to the output at the given
+// index
+func (s *splitter) inputsTo(out int, parts int) []int {
+	p := s.outs.Point(out)
+	ins := make([]int, parts)
+	for i := range ins {
+		ins[i] = s.ins.Index(p)
+		p[s.dim] += s.outs.Dim(s.dim)
+	}
+
+	return ins
+}
+
+// ****************************************
+// Maxout
+// ****************************************
+
+type maxout struct {
+	splitter
+
+	Pieces int
+}
+
+// Maxout returns a Layer that gives the maximum of 'k' linear pieces, which implements
+// badstudent.Operator. The pieces are given by splitting the last dimension (the channels) of its
+// input into 'k' equal parts, so that output channel 'c' is the maximum of channel 'c' in each
+// part. The pieces themselves should come from a Layer with weights; for example, a Maxout Node
+// with 'size' outputs is given by:
+//
+//	net.Add(Maxout(k), net.Add(Neurons(k*size), input))
+//
+// For more information, see: https://arxiv.org/abs/1302.4389
+func Maxout(k int) *maxout {
+	return &maxout{splitter: splitter{Dim: -1}, Pieces: k}
+}
+
+func (t *maxout) TypeString() string {
+	return "maxout"
+}
+
+func (t *maxout) setDims(inputs []*bs.Node) error {
+	if t.Pieces < 1 {
+		return errors.Errorf("Number of pieces must be ≥ 1 (%d)", t.Pieces)
+	}
+
+	return t.splitter.setDims("Maxout", inputs, t.Pieces)
+}
+
+// max returns the index of the input with the highest value for the given output
+func (t *maxout) max(inputs []float64, out int) int {
+	ins := t.inputsTo(out, t.Pieces)
+
+	best := ins[0]
+	for _, in := range ins[1:] {
+		if inputs[in] > inputs[best] {
+			best = in
+		}
+	}
+
+	return best
+}
+
+func (t *maxout) Finalize(n *bs.Node) error {
+	return t.setDims(n.InputNodes())
+}
+
+func (t *maxout) Get() interface{} {
+	return *t
+}
+
+func (t *maxout) Blank() interface{} {
+	return t
+}
+
+func (t *maxout) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	if err := t.setDims(inputs); err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	return tensors.NewTensor(t.outs.Dims), nil
+}
+
+func (t *maxout) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	f := func(v int) {
+		values[v] = inputs[t.max(inputs, v)]
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+}
+
+func (t *maxout) InputDeltas(n *bs.Node) []float64 {
+	inputs := n.AllInputs()
+	ds := make([]float64, n.NumInputs())
+
+	// the maximum is found again (instead of being stored by Evaluate) so that it's always from the
+	// same inputs as the deltas
+	f := func(out int) {
+		ds[t.max(inputs, out)] = n.Delta(out)
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, n.Size(), f, opsPerThread, threadsPerCPU)
+
+	return ds
+}
+
+// ****************************************
+// Gated Linear Units
+// ****************************************
+
+// the activations of the gates for each type of gated linear unit
+const (
+	gluLogistic int = iota
+	gluGELU
+	gluSiLU
+)
+
+type glu struct {
+	splitter
+
+	// the activation applied to the gate, given by one of the glu* constants
+	Act int
+}
+
+// GLU returns a gated linear unit, which implements badstudent.Operator. Its input is split in half
+// along the given dimension, and the first half is multiplied by Logistic of the second half. The
+// dimension may be negative to count from the end, so that -1 splits the last dimension.
+//
+// For more information, see: https://arxiv.org/abs/1612.08083
+func GLU(dim int) *glu {
+	return &glu{splitter: splitter{Dim: dim}, Act: gluLogistic}
+}
+
+// GeGLU returns a gated linear unit that uses GELU instead of Logistic for the gate. It is
+// otherwise the same as GLU.
+//
+// For more information, see: https://arxiv.org/abs/2002.05202
+func GeGLU(dim int) *glu {
+	return &glu{splitter: splitter{Dim: dim}, Act: gluGELU}
+}
+
+// SwiGLU returns a gated linear unit that uses SiLU (Swish with a beta of 1) instead of Logistic
+// for the gate. It is otherwise the same as GLU.
+//
+// For more information, see: https://arxiv.org/abs/2002.05202
+func SwiGLU(dim int) *glu {
+	return &glu{splitter: splitter{Dim: dim}, Act: gluSiLU}
+}
+
+// gate returns the activation of the gate, and its derivative, for the given input
+func (t *glu) gate(x float64) (float64, float64) {
+	switch t.Act {
+	case gluGELU:
+		cdf := 0.5 * (1 + math.Erf(x/math.Sqrt2))
+		return x * cdf, cdf + x*math.Exp(-0.5*x*x)/math.Sqrt(2*math.Pi)
+	case gluSiLU:
+		s := logisticOf(x)
+		return x * s, s * (1 + x*(1-s))
+	default: // gluLogistic
+		s := logisticOf(x)
+		return s, s * (1 - s)
+	}
+}
+
+func (t *glu) TypeString() string {
+	// Act is checked by Finalize, but TypeString is still used to report the error, so it must not
+	// panic if Act was loaded out of range
+	if t.Act < gluLogistic || t.Act > gluSiLU {
+		return "glu"
+	}
+
+	return [...]string{"glu", "geglu", "swiglu"}[t.Act]
+}
+
+func (t *glu) Finalize(n *bs.Node) error {
+	if t.Act < gluLogistic || t.Act > gluSiLU {
+		return errors.Errorf("Gate activation is out of range (%d)", t.Act)
+	}
+
+	return t.setDims("GLU", n.InputNodes(), 2)
+}
+
+func (t *glu) Get() interface{} {
+	return *t
+}
+
+func (t *glu) Blank() interface{} {
+	return t
+}
+
+func (t *glu) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	if err := t.setDims("GLU", inputs, 2); err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	return tensors.NewTensor(t.outs.Dims), nil
+}
+
+func (t *glu) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	f := func(v int) {
+		ins := t.inputsTo(v, 2)
+		g, _ := t.gate(inputs[ins[1]])
+		values[v] = inputs[ins[0]] * g
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+}
+
+func (t *glu) InputDeltas(n *bs.Node) []float64 {
+	inputs := n.AllInputs()
+	ds := make([]float64, n.NumInputs())
+
+	f := func(out int) {
+		ins := t.inputsTo(out, 2)
+		g, deriv := t.gate(inputs[ins[1]])
+
+		ds[ins[0]] = n.Delta(out) * g
+		ds[ins[1]] = n.Delta(out) * inputs[ins[0]] * deriv
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, n.Size(), f, opsPerThread, threadsPerCPU)
+
+	return ds
+}
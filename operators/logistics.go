@@ -92,3 +92,69 @@ func (t softsign) Deriv(n *bs.Node, index int) float64 {
 	// 1 / (|in| + 1)^2
 	return math.Pow(math.Abs(n.InputValue(index))+1, 2)
 }
+
+// ****************************************
+// Hard Sigmoid
+// ****************************************
+
+type hardSigmoid int8
+
+// HardSigmoid returns a piecewise linear approximation of Logistic, given by x/6 + 1/2, limited to
+// the range [0, 1].
+func HardSigmoid() hardSigmoid {
+	return hardSigmoid(0)
+}
+
+func (t hardSigmoid) TypeString() string {
+	return "hard-sigmoid"
+}
+
+func (t hardSigmoid) Finalize(n *bs.Node) error {
+	return nil
+}
+
+func (t hardSigmoid) Value(in float64, index int) float64 {
+	return math.Max(0, math.Min(1, in/6+0.5))
+}
+
+func (t hardSigmoid) Deriv(n *bs.Node, index int) float64 {
+	if in := n.InputValue(index); in > -3 && in < 3 {
+		return 1.0 / 6
+	}
+	return 0
+}
+
+// ****************************************
+// Hard Swish
+// ****************************************
+
+type hardSwish int8
+
+// HardSwish returns a piecewise approximation of SiLU, given by x * HardSigmoid(x).
+//
+// For more information, see: https://arxiv.org/abs/1905.02244
+func HardSwish() hardSwish {
+	return hardSwish(0)
+}
+
+func (t hardSwish) TypeString() string {
+	return "hard-swish"
+}
+
+func (t hardSwish) Finalize(n *bs.Node) error {
+	return nil
+}
+
+func (t hardSwish) Value(in float64, index int) float64 {
+	return in * math.Max(0, math.Min(1, in/6+0.5))
+}
+
+func (t hardSwish) Deriv(n *bs.Node, index int) float64 {
+	in := n.InputValue(index)
+	if in <= -3 {
+		return 0
+	} else if in >= 3 {
+		return 1
+	}
+	return (2*in + 3) / 6
+}
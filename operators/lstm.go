@@ -1,11 +1,7 @@
 package operators
 
 import (
-	"github.com/pkg/errors"
 	bs "github.com/sharnoff/badstudent"
-	"github.com/sharnoff/badstudent/utils"
-	"github.com/sharnoff/tensors"
-	"runtime"
 )
 
 // LSTM creates a standard LSTM unit, given the Nodes to input to the forget, ignore, and select
@@ -33,124 +29,57 @@ func LSTM(net *bs.Network, size int, update, ignore, forget, sel *bs.Node) (outp
 	return sGate, cs, csLoop
 }
 
-// ****************************************
-// Add
-// ****************************************
-
-type add int8
-
-// Add returns an elementwise addition operator that implements badstudent.Operator. All inputs to
-// its Node must have size equal to the Node.
-func Add() add {
-	return add(0)
-}
-
-func (t add) TypeString() string {
-	return "add"
-}
-
-func (t add) Finalize(n *bs.Node) error {
-	s := n.Input(0).Size()
-	for i := 1; i < n.NumInputNodes(); i++ {
-		if n.Input(i).Size() != s {
-			return errors.Errorf("All inputs must have equal size (n.InputSize(%d) (%d) != n.InputSize(%d) (%d))",
-				i, n.Input(i).Size(), 0, n.Input(0).Size())
-		}
-	}
-
-	return nil
-}
-
-func (t add) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
-	return tensors.NewTensor(inputs[0].Dims()), nil
-}
-
-func (t add) Evaluate(n *bs.Node, values []float64) {
-	inputs := n.AllInputs()
-
-	f := func(i int) {
-		values[i] = inputs[i]
-		for in := 1; in < n.NumInputNodes(); in++ {
-			values[i] += inputs[in*n.Size()+i]
-		}
-	}
-
-	// just random constants. Have not been optimized
-	opsPerThread, threadsPerCPU := runtime.NumCPU()*2, 1
-	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
-}
-
-func (t add) InputDeltas(n *bs.Node) []float64 {
-	ds := make([]float64, n.NumInputs())
-
-	f := func(i int) {
-		ds[i] = n.Delta(i % n.Size())
-	}
-
-	// just random constants. Have not been optimized
-	opsPerThread, threadsPerCPU := runtime.NumCPU()*2, 1
-	utils.MultiThread(0, n.Size(), f, opsPerThread, threadsPerCPU)
-
-	return ds
+// GRU creates a gated recurrent unit with the given size, taking 'input' as the input at each time
+// step. Unlike LSTM, the gates and their weights are all created internally: the reset and update
+// gates are each Neurons followed by Logistic, with both the input and the previous hidden state
+// as their inputs. All of the Nodes it creates are named, prefixed by "gru".
+//
+// Returns the hidden state, without delay, so it can be used as Network outputs, in addition to
+// the hidden state with a delay of 1, which can be used to feed into other parts of the Network.
+//
+// For more information, see: https://arxiv.org/abs/1406.1078
+func GRU(net *bs.Network, size int, input *bs.Node) (hidden, hiddenDelay *bs.Node) {
+	hLoop := net.Placeholder([]int{size}) // hidden-state loop
+
+	update := net.Add(Neurons(size), input, hLoop).SetName("gru update neurons")
+	update = net.Add(Logistic(), update).SetName("gru update gate")
+	reset := net.Add(Neurons(size), input, hLoop).SetName("gru reset neurons")
+	reset = net.Add(Logistic(), reset).SetName("gru reset gate")
+
+	// the candidate hidden state, from the input and the reset part of the previous state
+	rGate := net.Add(Mult(), reset, hLoop).SetName("gru reset")
+	cand := net.Add(Neurons(size), input, rGate).SetName("gru candidate neurons")
+	cand = net.Add(Tanh(), cand).SetName("gru candidate")
+
+	// h = (1 - z) * h' + z * candidate
+	keep := net.Add(oneMinus(0), update).SetName("gru keep gate")
+	kGate := net.Add(Mult(), keep, hLoop).SetName("gru keep")
+	uGate := net.Add(Mult(), update, cand).SetName("gru update")
+	h := net.Add(Add(), kGate, uGate).SetName("gru hidden")
+	hLoop.Replace(Identity(), h).SetDelay(1).SetName("gru hidden loop")
+
+	return h, hLoop
 }
 
 // ****************************************
-// Mult
+// One Minus
 // ****************************************
 
-type mult int8
-
-// Mult returns an elementwise multiplication operator that implements badstudent.Operator. All
-// inputs to its Node must have size equal to the Node.
-func Mult() mult {
-	return mult(0)
-}
+// oneMinus is the elementwise operator given by 1 - x, used for the gates in GRU
+type oneMinus int8
 
-func (t mult) TypeString() string {
-	return "multiply"
+func (t oneMinus) TypeString() string {
+	return "one-minus"
 }
 
-func (t mult) Finalize(n *bs.Node) error {
-	s := n.Input(0).Size()
-	for i := 1; i < n.NumInputNodes(); i++ {
-		if n.Input(i).Size() != s {
-			return errors.Errorf("All inputs must have equal size (n.InputSize(%d) (%d) != n.InputSize(%d) (%d))",
-				i, n.Input(i).Size(), 0, n.Input(0).Size())
-		}
-	}
-
+func (t oneMinus) Finalize(n *bs.Node) error {
 	return nil
 }
 
-func (t mult) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
-	return tensors.NewTensor(inputs[0].Dims()), nil
-}
-
-func (t mult) Evaluate(n *bs.Node, values []float64) {
-	inputs := n.AllInputs()
-
-	f := func(i int) {
-		values[i] = inputs[i]
-		for in := 1; in < n.NumInputNodes(); in++ {
-			values[i] *= inputs[in*n.Size()+i]
-		}
-	}
-
-	// just random constants. Have not been optimized
-	opsPerThread, threadsPerCPU := runtime.NumCPU()*2, 1
-	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+func (t oneMinus) Value(in float64, index int) float64 {
+	return 1 - in
 }
 
-func (t mult) InputDeltas(n *bs.Node) []float64 {
-	ds := make([]float64, n.NumInputs())
-
-	f := func(i int) {
-		ds[i] = n.Delta(i%n.Size()) * n.Value(i%n.Size()) / n.InputValue(i)
-	}
-
-	// just random constants. Have not been optimized
-	opsPerThread, threadsPerCPU := runtime.NumCPU()*2, 1
-	utils.MultiThread(0, n.Size(), f, opsPerThread, threadsPerCPU)
-
-	return ds
+func (t oneMinus) Deriv(n *bs.Node, index int) float64 {
+	return -1
 }
@@ -0,0 +1,642 @@
+// norm.go contains the normalization layers:
+// * Lagged Batch Normalization
+// * Layer Normalization
+// * RMS Normalization
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"github.com/sharnoff/tensors"
+	"math"
+)
+
+// ****************************************
+// Lagged Batch Normalization
+// ****************************************
+
+type batchNorm struct {
+	// whether or not the values are normalized per channel (the last dimension) instead of
+	// individually
+	PerChannel bool
+
+	// the factor that the running statistics are multiplied by before the statistics of each batch
+	// are added
+	RunDecay float64
+	Eps      float64
+
+	// the number of values in each channel; 1 if not PerChannel
+	Group int
+
+	// the scale (gamma) for each channel, followed by the shift (beta)
+	Ws []float64
+
+	// the running averages of the statistics of each channel, used outside of training
+	RunMean, RunVar []float64
+
+	// the statistics of each channel from the most recent batch, used during training once
+	// HasStats is true
+	BatchMean, BatchVar []float64
+	HasStats            bool
+
+	// the sums of the values and their squares for each channel over the current batch, and the
+	// number of samples that have been added to them
+	Sums, Squares []float64
+	Count         int
+
+	// the statistics used by the most recent evaluation, which are kept for backpropagation
+	mean, vr []float64
+}
+
+// LaggedBatchNorm returns a Layer that normalizes by the statistics of earlier batches, which
+// implements badstudent.Adjustable and badstudent.BatchOperator. Each input value is normalized to
+// zero mean and unit variance, then scaled and shifted by learned weights, which start at one and
+// zero, respectively.
+//
+// This is not quite batch normalization. Because the Network is given one sample at a time, the
+// statistics of each batch are only known once it has finished, so each batch cannot be normalized
+// by its own statistics. During training, the values are instead normalized by the statistics of
+// the previous batch (as delimited by DataSupplier.BatchEnded). These lag one batch behind any
+// changes to the weights before the Node, and are treated as constants while backpropagating, so
+// the deltas do not include the terms that would pass through the mean and variance. The running
+// averages of the statistics are used during testing and GetOutputs.
+//
+// Until the first batch has finished, there are no earlier statistics. Within the first batch, the
+// statistics of the batch so far are used instead, so they depend on the order of the samples; for
+// the very first sample, the running averages are used, which start at a mean of zero and variance
+// of one.
+//
+// A batch with only one value in each channel has no variance. If the first batch is one of these,
+// as when training with a batch size of one without Spatial, EndBatch returns an error, so training
+// stops. Later batches like this, such as a partial batch at the end of the data, are ignored.
+//
+// The rate at which the running averages change can be set by Decay, and the constant added to the
+// variance for numerical stability by Epsilon. They default to 0.9 and 1e-5, respectively.
+//
+// For more information on batch normalization, see: https://arxiv.org/abs/1502.03167
+func LaggedBatchNorm() *batchNorm {
+	return &batchNorm{
+		RunDecay: 0.9,
+		Eps:      1e-5,
+	}
+}
+
+// ***************************************************
+// Customization Functions
+// ***************************************************
+
+// Spatial changes the normalization to be per channel, taking the last dimension of the values as
+// the channels, as with the outputs of Conv with Depth. All of the values in each channel share
+// their statistics and weights.
+func (b *batchNorm) Spatial() *batchNorm {
+	b.PerChannel = true
+	return b
+}
+
+// Decay sets the factor that the running averages are multiplied by at the end of each batch. The
+// statistics of the batch are given the remaining weight of 1 - decay.
+func (b *batchNorm) Decay(decay float64) *batchNorm {
+	b.RunDecay = decay
+	return b
+}
+
+// Epsilon sets the constant added to the variance before dividing by its square root.
+func (b *batchNorm) Epsilon(eps float64) *batchNorm {
+	b.Eps = eps
+	return b
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+func (b *batchNorm) channels() int {
+	return len(b.Ws) / 2
+}
+
+// stats returns the mean and variance of each channel that should currently be used
+func (b *batchNorm) stats(n *bs.Node) ([]float64, []float64) {
+	if !n.Training() || (!b.HasStats && b.Count*b.Group <= 1) {
+		return b.RunMean, b.RunVar
+	} else if !b.HasStats {
+		return b.batchStats()
+	}
+
+	return b.BatchMean, b.BatchVar
+}
+
+// batchStats returns the mean and (biased) variance of each channel over the current batch so far.
+// It assumes that Count is not zero.
+func (b *batchNorm) batchStats() (mean, vr []float64) {
+	total := float64(b.Count * b.Group)
+
+	mean, vr = make([]float64, len(b.Sums)), make([]float64, len(b.Sums))
+	for c := range b.Sums {
+		mean[c] = b.Sums[c] / total
+		vr[c] = math.Max(0, b.Squares[c]/total-mean[c]*mean[c])
+	}
+
+	return mean, vr
+}
+
+// normalized returns the normalized value of the input at the given index, before being scaled
+// and shifted, using the statistics from the most recent evaluation
+func (b *batchNorm) normalized(n *bs.Node, index int) float64 {
+	c := index / b.Group
+	return (n.InputValue(index) - b.mean[c]) / math.Sqrt(b.vr[c]+b.Eps)
+}
+
+// ***************************************************
+// Interface-required functions
+// ***************************************************
+
+func (b *batchNorm) TypeString() string {
+	return "lagged-batch-norm"
+}
+
+func (b *batchNorm) Finalize(n *bs.Node) error {
+	// if it's been loaded from a file...
+	if len(b.Ws) != 0 {
+		return nil
+	}
+
+	if b.Eps <= 0 {
+		return errors.Errorf("Epsilon must be > 0 (%v)", b.Eps)
+	} else if b.RunDecay < 0 || b.RunDecay > 1 {
+		return errors.Errorf("Decay must be within the range [0, 1] (%v)", b.RunDecay)
+	}
+
+	channels := n.Size()
+	if b.PerChannel {
+		dims := n.Dims()
+		channels = dims[len(dims)-1]
+	}
+
+	b.Group = n.Size() / channels
+	b.Ws = make([]float64, 2*channels)
+
+	b.RunMean = make([]float64, channels)
+	b.RunVar = make([]float64, channels)
+	b.BatchMean = make([]float64, channels)
+	b.BatchVar = make([]float64, channels)
+	for c := range b.RunVar {
+		b.RunVar[c] = 1
+	}
+
+	b.Sums = make([]float64, channels)
+	b.Squares = make([]float64, channels)
+	return nil
+}
+
+func (b *batchNorm) Get() interface{} {
+	return *b
+}
+
+func (b *batchNorm) Blank() interface{} {
+	return b
+}
+
+// Set initializes the scales to 1 and the shifts to 0. It is called during finalization instead of
+// the Network's default Initializer.
+func (b *batchNorm) Set(n *bs.Node, weights []float64) {
+	for c := 0; c < b.channels(); c++ {
+		weights[c] = 1
+		weights[b.channels()+c] = 0
+	}
+}
+
+func (b *batchNorm) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	ls := make([]tensors.Tensor, len(inputs))
+	for i := range ls {
+		ls[i] = inputs[i].Shape()
+	}
+
+	return bs.ConcatShape(ls)
+}
+
+func (b *batchNorm) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	if n.Training() && !n.Replaying() {
+		for i, in := range inputs {
+			c := i / b.Group
+			b.Sums[c] += in
+			b.Squares[c] += in * in
+		}
+
+		b.Count++
+	}
+
+	b.mean, b.vr = b.stats(n)
+
+	f := func(i int) {
+		c := i / b.Group
+		values[i] = b.Ws[c]*b.normalized(n, i) + b.Ws[b.channels()+c]
+	}
+
+	opsPerThread, threadsPerCPU := 10, 1
+	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+}
+
+// The statistics are treated as constants, as they are from an earlier batch. Within the first
+// batch, where they include the current sample, this is only an approximation.
+func (b *batchNorm) InputDeltas(n *bs.Node) []float64 {
+	ds := make([]float64, n.NumInputs())
+
+	f := func(i int) {
+		c := i / b.Group
+		ds[i] = n.Delta(i) * b.Ws[c] / math.Sqrt(b.vr[c]+b.Eps)
+	}
+
+	opsPerThread, threadsPerCPU := 10, 1
+	utils.MultiThread(0, len(ds), f, opsPerThread, threadsPerCPU)
+
+	return ds
+}
+
+func (b *batchNorm) Grad(n *bs.Node, index int) float64 {
+	c := index % b.channels()
+	start := c * b.Group
+
+	var sum float64
+	for i := start; i < start+b.Group; i++ {
+		if index < b.channels() { // scale
+			sum += n.Delta(i) * b.normalized(n, i)
+		} else { // shift
+			sum += n.Delta(i)
+		}
+	}
+
+	return sum
+}
+
+func (b *batchNorm) Weights() []float64 {
+	return b.Ws
+}
+
+// EndBatch replaces the batch statistics with those of the batch that has just finished, and adds
+// them to the running averages. Batches with only one value in each channel are ignored, because
+// their variance is always zero, unless there are no earlier statistics, in which case EndBatch
+// returns an error.
+func (b *batchNorm) EndBatch(n *bs.Node) error {
+	if b.Count == 0 {
+		return nil
+	}
+
+	total := float64(b.Count * b.Group)
+
+	if total == 1 && !b.HasStats {
+		return errors.Errorf("Batch has only one value in each channel, so no variance (batch size must be > 1 without Spatial)")
+	} else if total > 1 {
+		mean, vr := b.batchStats()
+		copy(b.BatchMean, mean)
+		copy(b.BatchVar, vr)
+
+		for c := range mean {
+			// the running variance is unbiased, as it is an estimate of the variance of all inputs
+			unbiased := vr[c] * total / (total - 1)
+
+			b.RunMean[c] = b.RunDecay*b.RunMean[c] + (1-b.RunDecay)*mean[c]
+			b.RunVar[c] = b.RunDecay*b.RunVar[c] + (1-b.RunDecay)*unbiased
+		}
+
+		b.HasStats = true
+	}
+
+	for c := range b.Sums {
+		b.Sums[c], b.Squares[c] = 0, 0
+	}
+
+	b.Count = 0
+	return nil
+}
+
+// ****************************************
+// Layer Normalization (and RMS Normalization)
+// ****************************************
+
+type layerNorm struct {
+	// the indexes of the dimensions (as given by Node.Dims) that are normalized over. If empty, all
+	// dimensions are used.
+	Over []int
+
+	// whether or not the values are only divided by their root mean square, without subtracting
+	// the mean
+	RMS bool
+
+	// whether or not there are learned shifts in addition to the scales
+	Shift bool
+
+	Eps float64
+
+	// the scales for each position within the normalized dimensions, followed by the shifts, if
+	// there are any
+	Ws []float64
+
+	// the indexes of the values in each set that is normalized together, and the indexes of the
+	// values that use each scale (and shift). Both are determined by Over, and so are not saved.
+	groups  [][]int
+	members [][]int
+
+	// the normalized values and the standard deviation (or root mean square) of each group, from
+	// the most recent evaluation
+	norm []float64
+	dev  []float64
+}
+
+// LayerNorm returns a layer normalization Layer, which implements badstudent.Adjustable. For each
+// sample, the values are normalized to zero mean and unit variance across the given dimensions
+// (as indexes of Node.Dims), and then scaled and shifted by learned weights, which start at one
+// and zero, respectively. Each set of values with the same position in the other dimensions is
+// normalized separately, and each position in the given dimensions has its own weights. If no
+// dimensions are given, all of them are used.
+//
+// Because LayerNorm does not depend on other samples, it behaves identically during training and
+// testing.
+//
+// The shifts can be removed with NoShift, and the constant added to the variance for numerical
+// stability can be set by Epsilon. It defaults to 1e-5.
+//
+// For more information, see: https://arxiv.org/abs/1607.06450
+func LayerNorm(dims ...int) *layerNorm {
+	return &layerNorm{
+		Over:  dims,
+		Shift: true,
+		Eps:   1e-5,
+	}
+}
+
+// RMSNorm returns a root mean square normalization Layer, which implements badstudent.Adjustable.
+// It is the same as LayerNorm, except that the values are only divided by their root mean square,
+// without subtracting the mean, and there are no shifts by default. Shifts can be added with
+// WithShift.
+//
+// For more information, see: https://arxiv.org/abs/1910.07467
+func RMSNorm(dims ...int) *layerNorm {
+	return &layerNorm{
+		Over: dims,
+		RMS:  true,
+		Eps:  1e-5,
+	}
+}
+
+// ***************************************************
+// Customization Functions
+// ***************************************************
+
+// NoShift removes the learned shifts, so that the normalized values are only scaled.
+func (l *layerNorm) NoShift() *layerNorm {
+	l.Shift = false
+	return l
+}
+
+// WithShift adds learned shifts after the normalized values are scaled, if there were not already.
+func (l *layerNorm) WithShift() *layerNorm {
+	l.Shift = true
+	return l
+}
+
+// Epsilon sets the constant added to the variance (or mean square) before taking its square root.
+func (l *layerNorm) Epsilon(eps float64) *layerNorm {
+	l.Eps = eps
+	return l
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+// numScales returns the number of scales, which is the same as the number of shifts, if there are
+// any
+func (l *layerNorm) numScales() int {
+	return len(l.members)
+}
+
+// setIndexes fills groups and members, given the dimensions of the Node. It returns an error if
+// Over is not valid.
+//
+// Because the values in each group are in increasing order, the position of a value within its
+// group is the same as the index of its scale in members.
+func (l *layerNorm) setIndexes(dims []int) error {
+	over := make([]bool, len(dims))
+	if len(l.Over) == 0 {
+		for d := range over {
+			over[d] = true
+		}
+	}
+
+	for _, d := range l.Over {
+		if d < 0 || d >= len(dims) {
+			return errors.Errorf("Dimension %d is out of range (Node has %d dimensions)", d, len(dims))
+		} else if over[d] {
+			return errors.Errorf("Dimension %d was given more than once", d)
+		}
+
+		over[d] = true
+	}
+
+	// the sizes of the normalized dimensions and the others, in the same order
+	var inDims, outDims []int
+	for d := range dims {
+		if over[d] {
+			inDims = append(inDims, dims[d])
+		} else {
+			outDims = append(outDims, dims[d])
+		}
+	}
+
+	if len(outDims) == 0 {
+		outDims = []int{1}
+	}
+
+	in, out := utils.NewMultiDim(inDims), utils.NewMultiDim(outDims)
+	all := utils.NewMultiDim(dims)
+
+	l.groups = make([][]int, out.Size())
+	l.members = make([][]int, in.Size())
+
+	inPoint := make([]int, len(inDims))
+	outPoint := make([]int, len(outDims))
+	for i := 0; i < all.Size(); i++ {
+		p := all.Point(i)
+
+		inPoint, outPoint = inPoint[:0], outPoint[:0]
+		for d := range p {
+			if over[d] {
+				inPoint = append(inPoint, p[d])
+			} else {
+				outPoint = append(outPoint, p[d])
+			}
+		}
+
+		if len(outPoint) == 0 {
+			outPoint = append(outPoint, 0)
+		}
+
+		g, m := out.Index(outPoint), in.Index(inPoint)
+		l.groups[g] = append(l.groups[g], i)
+		l.members[m] = append(l.members[m], i)
+	}
+
+	return nil
+}
+
+// ***************************************************
+// Interface-required functions
+// ***************************************************
+
+func (l *layerNorm) TypeString() string {
+	if l.RMS {
+		return "rms-norm"
+	}
+
+	return "layer-norm"
+}
+
+func (l *layerNorm) Finalize(n *bs.Node) error {
+	if l.Eps <= 0 {
+		return errors.Errorf("Epsilon must be > 0 (%v)", l.Eps)
+	}
+
+	if err := l.setIndexes(n.Dims()); err != nil {
+		return err
+	}
+
+	l.norm = make([]float64, n.Size())
+	l.dev = make([]float64, len(l.groups))
+
+	// if it's been loaded from a file...
+	if len(l.Ws) != 0 {
+		return nil
+	}
+
+	if l.Shift {
+		l.Ws = make([]float64, 2*l.numScales())
+	} else {
+		l.Ws = make([]float64, l.numScales())
+	}
+
+	return nil
+}
+
+func (l *layerNorm) Get() interface{} {
+	return *l
+}
+
+func (l *layerNorm) Blank() interface{} {
+	return l
+}
+
+// Set initializes the scales to 1 and the shifts to 0. It is called during finalization instead of
+// the Network's default Initializer.
+func (l *layerNorm) Set(n *bs.Node, weights []float64) {
+	for i := range weights {
+		if i < l.numScales() {
+			weights[i] = 1
+		} else {
+			weights[i] = 0
+		}
+	}
+}
+
+func (l *layerNorm) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	ls := make([]tensors.Tensor, len(inputs))
+	for i := range ls {
+		ls[i] = inputs[i].Shape()
+	}
+
+	return bs.ConcatShape(ls)
+}
+
+func (l *layerNorm) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	f := func(g int) {
+		group := l.groups[g]
+		size := float64(len(group))
+
+		var mean float64
+		if !l.RMS {
+			for _, i := range group {
+				mean += inputs[i]
+			}
+
+			mean /= size
+		}
+
+		var sq float64
+		for _, i := range group {
+			sq += (inputs[i] - mean) * (inputs[i] - mean)
+		}
+
+		l.dev[g] = math.Sqrt(sq/size + l.Eps)
+
+		for pos, i := range group {
+			l.norm[i] = (inputs[i] - mean) / l.dev[g]
+
+			values[i] = l.Ws[pos] * l.norm[i]
+			if l.Shift {
+				values[i] += l.Ws[l.numScales()+pos]
+			}
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(l.groups), f, opsPerThread, threadsPerCPU)
+}
+
+func (l *layerNorm) InputDeltas(n *bs.Node) []float64 {
+	ds := make([]float64, n.NumInputs())
+
+	f := func(g int) {
+		group := l.groups[g]
+		size := float64(len(group))
+
+		// the deltas of the normalized values are given by: delta * scale. We need both their
+		// mean and the mean of their product with the normalized values
+		var mean, dot float64
+		for pos, i := range group {
+			d := n.Delta(i) * l.Ws[pos]
+			mean += d
+			dot += d * l.norm[i]
+		}
+
+		mean /= size
+		dot /= size
+
+		// with RMS normalization, the mean is not subtracted, so it does not affect the deltas
+		if l.RMS {
+			mean = 0
+		}
+
+		for pos, i := range group {
+			d := n.Delta(i) * l.Ws[pos]
+			ds[i] = (d - mean - l.norm[i]*dot) / l.dev[g]
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(l.groups), f, opsPerThread, threadsPerCPU)
+
+	return ds
+}
+
+func (l *layerNorm) Grad(n *bs.Node, index int) float64 {
+	isScale := index < l.numScales()
+
+	var sum float64
+	for _, i := range l.members[index%l.numScales()] {
+		if isScale {
+			sum += n.Delta(i) * l.norm[i]
+		} else {
+			sum += n.Delta(i)
+		}
+	}
+
+	return sum
+}
+
+func (l *layerNorm) Weights() []float64 {
+	return l.Ws
+}
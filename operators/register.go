@@ -19,11 +19,52 @@ func init() {
 		func() bs.Operator { return Softmax() },
 		func() bs.Operator { return PReLU() },
 		func() bs.Operator { return Conv() },
-		func() bs.Operator { return Mult() },
+		func() bs.Operator { return &arithmetic{kind: arithMult, legacy: true} },
 		func() bs.Operator { return Tanh() },
 		func() bs.Operator { return ReLU() },
 		func() bs.Operator { return ELU() },
+		func() bs.Operator { return &arithmetic{kind: arithAdd, legacy: true} },
+		func() bs.Operator { return LaggedBatchNorm() },
+		func() bs.Operator { return LayerNorm() },
+		func() bs.Operator { return RMSNorm() },
+		func() bs.Operator { return Dropout(0) },
+		func() bs.Operator { return AlphaDropout(0) },
+		func() bs.Operator { return Embedding(0, 0) },
+		func() bs.Operator { return Attention(0, 0) },
+		func() bs.Operator { return oneMinus(0) },
+		func() bs.Operator { return LSTMCell(0) },
+		func() bs.Operator { return GRUCell(0) },
+		func() bs.Operator { return Bidirectional(LSTMCell(0)) },
+		func() bs.Operator { return ConvTranspose() },
+		func() bs.Operator { return Upsample() },
+		func() bs.Operator { return GlobalAvgPool() },
+		func() bs.Operator { return GlobalMaxPool() },
+		func() bs.Operator { return AdaptiveAvgPool() },
+		func() bs.Operator { return AdaptiveMaxPool() },
+		func() bs.Operator { return Reshape() },
+		func() bs.Operator { return Transpose() },
+		func() bs.Operator { return Slice(0, 0, 0) },
+		func() bs.Operator { return Pad() },
 		func() bs.Operator { return Add() },
+		func() bs.Operator { return Mult() },
+		func() bs.Operator { return Sub() },
+		func() bs.Operator { return Div() },
+		func() bs.Operator { return Max() },
+		func() bs.Operator { return Min() },
+		func() bs.Operator { return Pow() },
+		func() bs.Operator { return Scale(0) },
+		func() bs.Operator { return Shift(0) },
+		func() bs.Operator { return GELU() },
+		func() bs.Operator { return SiLU() },
+		func() bs.Operator { return Swish() },
+		func() bs.Operator { return Mish() },
+		func() bs.Operator { return SELU() },
+		func() bs.Operator { return HardSigmoid() },
+		func() bs.Operator { return HardSwish() },
+		func() bs.Operator { return Maxout(0) },
+		func() bs.Operator { return GLU(0) },
+		func() bs.Operator { return GeGLU(0) },
+		func() bs.Operator { return SwiGLU(0) },
 	}
 
 	if err := bs.RegisterAll(list); err != nil {
@@ -35,13 +76,14 @@ func init() {
 		"pool-padding": 0,
 		"conv-bias":    1,
 		"conv-padding": 0,
+		"pad-value":    0,
 	}
 }
 
 var defaultValue map[string]float64
 
 // SetDefault sets the default values for certain Operators. The values that can be
-// set are: "neurons-bias", "pool-padding", "conv-bias", and "conv-padding".
+// set are: "neurons-bias", "pool-padding", "conv-bias", "conv-padding", and "pad-value".
 func SetDefault(name string, value float64) error {
 	if _, ok := defaultValue[name]; !ok {
 		return errors.Errorf("Value with name %q does not exist", name)
@@ -4,6 +4,8 @@
 // * Parametric ReLU
 // * ELU
 // * Softplus (because it's similar)
+// * GELU, SiLU, Swish and Mish (because they're smooth versions of it)
+// * SELU
 package operators
 
 import (
@@ -199,3 +201,222 @@ func (t softplus) Deriv(n *bs.Node, index int) float64 {
 	// 1 / (1 + e^-x)
 	return 1.0 / (1 + math.Exp(-n.InputValue(index)))
 }
+
+// ****************************************
+// GELU
+// ****************************************
+
+type gelu int8
+
+// GELU (Gaussian error linear unit) returns the activation function given by x * Φ(x), where Φ is
+// the cumulative distribution function of the standard normal distribution. It is calculated
+// exactly, instead of with the usual tanh approximation.
+//
+// For more information, see: https://arxiv.org/abs/1606.08415
+func GELU() gelu {
+	return gelu(0)
+}
+
+func (t gelu) TypeString() string {
+	return "gelu"
+}
+
+func (t gelu) Finalize(n *bs.Node) error {
+	return nil
+}
+
+func (t gelu) Value(in float64, index int) float64 {
+	return 0.5 * in * (1 + math.Erf(in/math.Sqrt2))
+}
+
+func (t gelu) Deriv(n *bs.Node, index int) float64 {
+	// Φ(x) + x * φ(x)
+	in := n.InputValue(index)
+	return 0.5*(1+math.Erf(in/math.Sqrt2)) + in*math.Exp(-0.5*in*in)/math.Sqrt(2*math.Pi)
+}
+
+// ****************************************
+// SiLU
+// ****************************************
+
+type silu int8
+
+// SiLU (sigmoid linear unit) returns the activation function given by x * logistic(x). It is the
+// same as Swish with a constant beta of 1.
+func SiLU() silu {
+	return silu(0)
+}
+
+func (t silu) TypeString() string {
+	return "silu"
+}
+
+func (t silu) Finalize(n *bs.Node) error {
+	return nil
+}
+
+func (t silu) Value(in float64, index int) float64 {
+	return in * logisticOf(in)
+}
+
+func (t silu) Deriv(n *bs.Node, index int) float64 {
+	in := n.InputValue(index)
+	s := logisticOf(in)
+	return s * (1 + in*(1-s))
+}
+
+// ****************************************
+// Swish
+// ****************************************
+
+type swish struct {
+	// the single weight: beta
+	Ws []float64
+}
+
+// Swish returns the activation function given by x * logistic(beta * x), where beta is a single
+// weight that is learned for the whole Node. Swish implements badstudent.Adjustable. Beta starts at
+// 1, where Swish is the same as SiLU; it is set during finalization instead of by the Network's
+// default Initializer.
+//
+// For more information, see: https://arxiv.org/abs/1710.05941
+func Swish() *swish {
+	return &swish{}
+}
+
+func (t *swish) TypeString() string {
+	return "swish"
+}
+
+func (t *swish) Finalize(n *bs.Node) error {
+	// if it's been loaded from a file...
+	if len(t.Ws) != 0 {
+		return nil
+	}
+
+	t.Ws = make([]float64, 1)
+	return nil
+}
+
+func (t *swish) Get() interface{} {
+	return *t
+}
+
+func (t *swish) Blank() interface{} {
+	return t
+}
+
+// Set initializes beta to 1. It is called during finalization instead of the Network's default
+// Initializer.
+func (t *swish) Set(n *bs.Node, weights []float64) {
+	weights[0] = 1
+}
+
+func (t *swish) Value(in float64, index int) float64 {
+	return in * logisticOf(t.Ws[0]*in)
+}
+
+func (t *swish) Deriv(n *bs.Node, index int) float64 {
+	in, beta := n.InputValue(index), t.Ws[0]
+	s := logisticOf(beta * in)
+	return s * (1 + beta*in*(1-s))
+}
+
+func (t *swish) Weights() []float64 {
+	return t.Ws
+}
+
+func (t *swish) Grad(n *bs.Node, index int) float64 {
+	var g float64
+	for i := 0; i < n.Size(); i++ {
+		in := n.InputValue(i)
+		s := logisticOf(t.Ws[0] * in)
+		g += n.Delta(i) * in * in * s * (1 - s)
+	}
+
+	return g
+}
+
+// ****************************************
+// Mish
+// ****************************************
+
+type mish int8
+
+// Mish returns the activation function given by x * tanh(softplus(x)).
+//
+// For more information, see: https://arxiv.org/abs/1908.08681
+func Mish() mish {
+	return mish(0)
+}
+
+// softplusOf is a numerically stable version of ln(1 + e^x)
+func softplusOf(x float64) float64 {
+	if x > 20 {
+		return x
+	}
+
+	return math.Log1p(math.Exp(x))
+}
+
+func (t mish) TypeString() string {
+	return "mish"
+}
+
+func (t mish) Finalize(n *bs.Node) error {
+	return nil
+}
+
+func (t mish) Value(in float64, index int) float64 {
+	return in * math.Tanh(softplusOf(in))
+}
+
+func (t mish) Deriv(n *bs.Node, index int) float64 {
+	// tanh(sp(x)) + x * sech²(sp(x)) * logistic(x)
+	in := n.InputValue(index)
+	th := math.Tanh(softplusOf(in))
+	return th + in*(1-th*th)*logisticOf(in)
+}
+
+// ****************************************
+// SELU
+// ****************************************
+
+type selu int8
+
+// the constants for SELU, which make it self-normalizing
+const (
+	seluAlpha float64 = 1.6732632423543772848170429916717
+	seluScale float64 = 1.0507009873554804934193349852946
+)
+
+// SELU (scaled exponential linear unit) returns a scaled version of ELU, with constants chosen so
+// that activations tend towards zero mean and unit variance. It is intended to be used with
+// AlphaDropout.
+//
+// For more information, see: https://arxiv.org/abs/1706.02515
+func SELU() selu {
+	return selu(0)
+}
+
+func (t selu) TypeString() string {
+	return "selu"
+}
+
+func (t selu) Finalize(n *bs.Node) error {
+	return nil
+}
+
+func (t selu) Value(in float64, index int) float64 {
+	if in >= 0 {
+		return seluScale * in
+	}
+	return seluScale * seluAlpha * (math.Exp(in) - 1)
+}
+
+func (t selu) Deriv(n *bs.Node, index int) float64 {
+	if in := n.InputValue(index); in < 0 {
+		return seluScale * seluAlpha * math.Exp(in)
+	}
+	return seluScale
+}
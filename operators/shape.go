@@ -0,0 +1,448 @@
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"github.com/sharnoff/tensors"
+)
+
+// inputDims returns the dimensions of the single input Node that the named Operator requires
+func inputDims(name string, inputs []*bs.Node) ([]int, error) {
+	if len(inputs) != 1 {
+		return nil, errors.Errorf("%s must have exactly 1 input Node (has %d)", name, len(inputs))
+	}
+
+	return inputs[0].Dims(), nil
+}
+
+// ****************************************
+// Reshape
+// ****************************************
+
+type reshape struct {
+	// the output dimensions. One of them may be -1 until the Operator is finalized
+	Dims []int
+}
+
+// Reshape returns a Layer that gives its inputs with the given dimensions, without changing their
+// values or their order, which implements badstudent.Operator. The size of the Node must be equal
+// to the total size of its inputs; at most one dimension may be given as -1, in which case it is
+// calculated from the others.
+//
+// The inputs to the Node are taken in order, as given by *Node.AllInputs, so Reshape can also be
+// used to join several Nodes with explicit dimensions.
+func Reshape(dims ...int) *reshape {
+	return &reshape{Dims: dims}
+}
+
+func (r *reshape) TypeString() string {
+	return "reshape"
+}
+
+// setDims fills in any unknown dimension, given the total size of the inputs
+func (r *reshape) setDims(size int) error {
+	if len(r.Dims) == 0 {
+		return errors.Errorf("Reshape must have at least one dimension")
+	}
+
+	unknown, known := -1, 1
+	for d, dim := range r.Dims {
+		if dim == -1 && unknown == -1 {
+			unknown = d
+		} else if dim < 1 {
+			return errors.Errorf("Dims[%d] = %d, must be ≥ 1 (or -1 for at most one dimension)", d, dim)
+		} else {
+			known *= dim
+		}
+	}
+
+	if unknown != -1 {
+		if size%known != 0 {
+			return errors.Errorf("Size of inputs is not divisible by the given dimensions (%d %% %d != 0)", size, known)
+		}
+
+		r.Dims[unknown] = size / known
+	} else if known != size {
+		return errors.Errorf("Size of dimensions is not equal to the size of the inputs (%d != %d)", known, size)
+	}
+
+	return nil
+}
+
+func (r *reshape) Finalize(n *bs.Node) error {
+	return r.setDims(n.NumInputs())
+}
+
+func (r *reshape) Get() interface{} {
+	return *r
+}
+
+func (r *reshape) Blank() interface{} {
+	return r
+}
+
+func (r *reshape) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	var size int
+	for _, in := range inputs {
+		size += in.Size()
+	}
+
+	if err := r.setDims(size); err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	return tensors.NewTensor(append([]int(nil), r.Dims...)), nil
+}
+
+func (r *reshape) Evaluate(n *bs.Node, values []float64) {
+	copy(values, n.AllInputs())
+}
+
+func (r *reshape) InputDeltas(n *bs.Node) []float64 {
+	ds := make([]float64, n.NumInputs())
+	for i := range ds {
+		ds[i] = n.Delta(i)
+	}
+
+	return ds
+}
+
+// ****************************************
+// Transpose
+// ****************************************
+
+type transpose struct {
+	// output dimension 'd' is input dimension Perm[d]
+	Perm []int
+
+	// the dimensions of the inputs and outputs, set by Finalize
+	ins, outs *utils.MultiDim
+}
+
+// Transpose returns a Layer that permutes the dimensions of its input, which implements
+// badstudent.Operator. Dimension 'd' of the output is dimension perm[d] of the input, so that
+// Transpose(1, 0) swaps the two dimensions of a matrix. perm must contain each dimension of the
+// input exactly once.
+func Transpose(perm ...int) *transpose {
+	return &transpose{Perm: perm}
+}
+
+func (t *transpose) TypeString() string {
+	return "transpose"
+}
+
+// setDims sets the dimensions of the inputs and outputs, returning error if Perm does not match
+// the input
+func (t *transpose) setDims(inputs []*bs.Node) error {
+	dims, err := inputDims("Transpose", inputs)
+	if err != nil {
+		return err
+	} else if len(t.Perm) != len(dims) {
+		return errors.Errorf("Length of permutation must equal the number of input dimensions (%d != %d)", len(t.Perm), len(dims))
+	}
+
+	used := make([]bool, len(dims))
+	outs := make([]int, len(dims))
+	for d, p := range t.Perm {
+		if p < 0 || p >= len(dims) {
+			return errors.Errorf("Perm[%d] = %d, out of range [0, %d)", d, p, len(dims))
+		} else if used[p] {
+			return errors.Errorf("Perm contains dimension %d more than once", p)
+		}
+
+		used[p] = true
+		outs[d] = dims[p]
+	}
+
+	t.ins, t.outs = utils.NewMultiDim(dims), utils.NewMultiDim(outs)
+	return nil
+}
+
+// source returns the index of the input given to the output at the given index
+func (t *transpose) source(out int) int {
+	out_p := t.outs.Point(out)
+	in_p := make([]int, len(out_p))
+	for d, p := range t.Perm {
+		in_p[p] = out_p[d]
+	}
+
+	return t.ins.Index(in_p)
+}
+
+func (t *transpose) Finalize(n *bs.Node) error {
+	return t.setDims(n.InputNodes())
+}
+
+func (t *transpose) Get() interface{} {
+	return *t
+}
+
+func (t *transpose) Blank() interface{} {
+	return t
+}
+
+func (t *transpose) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	if err := t.setDims(inputs); err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	return tensors.NewTensor(t.outs.Dims), nil
+}
+
+func (t *transpose) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	f := func(v int) {
+		values[v] = inputs[t.source(v)]
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+}
+
+func (t *transpose) InputDeltas(n *bs.Node) []float64 {
+	ds := make([]float64, n.NumInputs())
+
+	// each input is given to exactly one output, so there's no need for atomics
+	f := func(out int) {
+		ds[t.source(out)] = n.Delta(out)
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, n.Size(), f, opsPerThread, threadsPerCPU)
+
+	return ds
+}
+
+// ****************************************
+// Slice
+// ****************************************
+
+type slice struct {
+	// the dimension that is sliced, and the range [Start, End) that is taken from it
+	Dim        int
+	Start, End int
+
+	// the dimensions of the inputs and outputs, set by Finalize
+	ins, outs *utils.MultiDim
+}
+
+// Slice returns a Layer that takes the values of its input from 'start' (inclusive) to 'end'
+// (exclusive) along the given dimension, which implements badstudent.Operator. All of the other
+// dimensions are unchanged. Inputs outside of the slice are given no deltas.
+//
+// To split a Node into multiple parts, see Split.
+func Slice(dim, start, end int) *slice {
+	return &slice{Dim: dim, Start: start, End: end}
+}
+
+// Split adds a Slice Node for each of the given sizes, which divide the input along the given
+// dimension in order. If the sizes add up to less than the size of that dimension, the values
+// after the last part are left out. For example, the gates of an LSTM can be given by a single
+// Neurons Node with:
+//
+//	gates := Split(net, net.Add(Neurons(4*size), input), 0, size, size, size, size)
+func Split(net *bs.Network, input *bs.Node, dim int, sizes ...int) []*bs.Node {
+	nodes := make([]*bs.Node, len(sizes))
+
+	var start int
+	for i, s := range sizes {
+		nodes[i] = net.Add(Slice(dim, start, start+s), input)
+		start += s
+	}
+
+	return nodes
+}
+
+func (s *slice) TypeString() string {
+	return "slice"
+}
+
+// setDims sets the dimensions of the inputs and outputs, returning error if the slice does not fit
+// the input
+func (s *slice) setDims(inputs []*bs.Node) error {
+	dims, err := inputDims("Slice", inputs)
+	if err != nil {
+		return err
+	} else if s.Dim < 0 || s.Dim >= len(dims) {
+		return errors.Errorf("Dimension to slice is out of range (%d not in [0, %d))", s.Dim, len(dims))
+	} else if s.Start < 0 || s.End > dims[s.Dim] || s.Start >= s.End {
+		return errors.Errorf("Slice [%d, %d) does not fit dimension %d (size %d)", s.Start, s.End, s.Dim, dims[s.Dim])
+	}
+
+	outs := make([]int, len(dims))
+	copy(outs, dims)
+	outs[s.Dim] = s.End - s.Start
+
+	s.ins, s.outs = utils.NewMultiDim(dims), utils.NewMultiDim(outs)
+	return nil
+}
+
+// source returns the index of the input given to the output at the given index
+func (s *slice) source(out int) int {
+	p := s.outs.Point(out)
+	p[s.Dim] += s.Start
+	return s.ins.Index(p)
+}
+
+func (s *slice) Finalize(n *bs.Node) error {
+	return s.setDims(n.InputNodes())
+}
+
+func (s *slice) Get() interface{} {
+	return *s
+}
+
+func (s *slice) Blank() interface{} {
+	return s
+}
+
+func (s *slice) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	if err := s.setDims(inputs); err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	return tensors.NewTensor(s.outs.Dims), nil
+}
+
+func (s *slice) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	f := func(v int) {
+		values[v] = inputs[s.source(v)]
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+}
+
+func (s *slice) InputDeltas(n *bs.Node) []float64 {
+	ds := make([]float64, n.NumInputs())
+
+	f := func(out int) {
+		ds[s.source(out)] = n.Delta(out)
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, n.Size(), f, opsPerThread, threadsPerCPU)
+
+	return ds
+}
+
+// ****************************************
+// Pad
+// ****************************************
+
+type pad struct {
+	// the amount of padding on both ends of each dimension
+	Padding      []int
+	PaddingValue float64
+
+	// the dimensions of the inputs and outputs, set by Finalize
+	ins, outs *utils.MultiDim
+}
+
+// Pad returns a Layer that adds the given amount of padding to both ends of each dimension of its
+// input, which implements badstudent.Operator. There must be one amount for each dimension; an
+// amount of 0 leaves that dimension unchanged. The padding has a constant value, which defaults to
+// zero and can be set by PadValue. The default can be set by SetDefault("pad-value").
+func Pad(dims ...int) *pad {
+	return &pad{Padding: dims, PaddingValue: defaultValue["pad-value"]}
+}
+
+// PadValue sets the value of the padding around the input.
+func (p *pad) PadValue(v float64) *pad {
+	p.PaddingValue = v
+	return p
+}
+
+func (p *pad) TypeString() string {
+	return "pad"
+}
+
+// setDims sets the dimensions of the inputs and outputs, returning error if the padding does not
+// match the input
+func (p *pad) setDims(inputs []*bs.Node) error {
+	dims, err := inputDims("Pad", inputs)
+	if err != nil {
+		return err
+	} else if len(p.Padding) != len(dims) {
+		return errors.Errorf("Padding has different length to input dimensions (%d != %d)", len(p.Padding), len(dims))
+	}
+
+	outs := make([]int, len(dims))
+	for d := range dims {
+		if p.Padding[d] < 0 {
+			return errors.Errorf("Padding[%d] = %d, must be ≥ 0", d, p.Padding[d])
+		}
+
+		outs[d] = dims[d] + 2*p.Padding[d]
+	}
+
+	p.ins, p.outs = utils.NewMultiDim(dims), utils.NewMultiDim(outs)
+	return nil
+}
+
+// source returns the index of the input given to the output at the given index, or -1 if it is
+// padding
+func (p *pad) source(out int) int {
+	out_p := mapSub(p.outs.Point(out), p.Padding)
+	for d := range out_p {
+		if out_p[d] < 0 || out_p[d] >= p.ins.Dim(d) {
+			return -1
+		}
+	}
+
+	return p.ins.Index(out_p)
+}
+
+func (p *pad) Finalize(n *bs.Node) error {
+	return p.setDims(n.InputNodes())
+}
+
+func (p *pad) Get() interface{} {
+	return *p
+}
+
+func (p *pad) Blank() interface{} {
+	return p
+}
+
+func (p *pad) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	if err := p.setDims(inputs); err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	return tensors.NewTensor(p.outs.Dims), nil
+}
+
+func (p *pad) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	f := func(v int) {
+		if in := p.source(v); in != -1 {
+			values[v] = inputs[in]
+		} else {
+			values[v] = p.PaddingValue
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+}
+
+func (p *pad) InputDeltas(n *bs.Node) []float64 {
+	ds := make([]float64, n.NumInputs())
+
+	f := func(out int) {
+		if in := p.source(out); in != -1 {
+			ds[in] = n.Delta(out)
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, n.Size(), f, opsPerThread, threadsPerCPU)
+
+	return ds
+}
@@ -0,0 +1,178 @@
+package operators
+
+import (
+	"github.com/pkg/errors"
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"github.com/sharnoff/tensors"
+	"math"
+)
+
+type upsample struct {
+	// the factor that each dimension is scaled by
+	Factors []int
+
+	// whether or not the values are interpolated linearly, instead of by nearest neighbor
+	Linear bool
+
+	// the dimensions of the inputs and outputs, set by Finalize
+	ins, outs *utils.MultiDim
+}
+
+// Upsample returns a Layer that increases the size of each dimension of its input by the given
+// factors, which implements badstudent.Operator. There must be one factor for each dimension of
+// the input; a factor of 1 leaves that dimension unchanged, as should be used for channels.
+//
+// By default, each output is given the value of the nearest input (i.e. each input is repeated).
+// Outputs can instead be interpolated between the nearest inputs with Bilinear.
+func Upsample(factors ...int) *upsample {
+	return &upsample{Factors: factors}
+}
+
+// Bilinear changes the upsampling to interpolate linearly between the nearest inputs in each
+// dimension (which is bilinear for two dimensions). Input and output values are both treated as
+// the centers of their regions, and positions beyond the edges use the values at the edges.
+func (u *upsample) Bilinear() *upsample {
+	u.Linear = true
+	return u
+}
+
+// ***************************************************
+// Helper Functions
+// ***************************************************
+
+// setDims sets the dimensions of the inputs and outputs, returning error if the factors do not
+// match the input
+func (u *upsample) setDims(inputs []*bs.Node) error {
+	if len(inputs) != 1 {
+		return errors.Errorf("Upsample must have exactly 1 input Node (has %d)", len(inputs))
+	}
+
+	dims := inputs[0].Dims()
+	if len(u.Factors) != len(dims) {
+		return errors.Errorf("Number of factors must equal the number of input dimensions (%d != %d)", len(u.Factors), len(dims))
+	}
+
+	outs := make([]int, len(dims))
+	for i := range dims {
+		if u.Factors[i] < 1 {
+			return errors.Errorf("Factors[%d] = %d, must be ≥ 1", i, u.Factors[i])
+		}
+
+		outs[i] = dims[i] * u.Factors[i]
+	}
+
+	u.ins, u.outs = utils.NewMultiDim(dims), utils.NewMultiDim(outs)
+	return nil
+}
+
+// sources returns the inputs (and their weights) that the given output is taken from
+func (u *upsample) sources(out int) (ins []int, weights []float64) {
+	out_p := u.outs.Point(out)
+
+	if !u.Linear {
+		for i := range out_p {
+			out_p[i] /= u.Factors[i]
+		}
+
+		return []int{u.ins.Index(out_p)}, []float64{1}
+	}
+
+	// the two nearest inputs in each dimension, and the weight of the upper one
+	lower, upper := make([]int, len(out_p)), make([]int, len(out_p))
+	frac := make([]float64, len(out_p))
+	for i := range out_p {
+		pos := (float64(out_p[i])+0.5)/float64(u.Factors[i]) - 0.5
+		pos = math.Max(0, math.Min(float64(u.ins.Dim(i)-1), pos))
+
+		lower[i] = int(math.Floor(pos))
+		upper[i] = lower[i]
+		if upper[i]+1 < u.ins.Dim(i) {
+			upper[i]++
+		}
+
+		frac[i] = pos - float64(lower[i])
+	}
+
+	// each combination of lower and upper, given by the bits of c
+	p := make([]int, len(out_p))
+	for c := 0; c < 1<<uint(len(out_p)); c++ {
+		w := 1.0
+		for i := range p {
+			if c&(1<<uint(i)) == 0 {
+				p[i], w = lower[i], w*(1-frac[i])
+			} else {
+				p[i], w = upper[i], w*frac[i]
+			}
+		}
+
+		if w != 0 {
+			ins = append(ins, u.ins.Index(p))
+			weights = append(weights, w)
+		}
+	}
+
+	return ins, weights
+}
+
+// ***************************************************
+// Interface-required functions
+// ***************************************************
+
+func (u *upsample) TypeString() string {
+	return "upsample"
+}
+
+func (u *upsample) Finalize(n *bs.Node) error {
+	return u.setDims(n.InputNodes())
+}
+
+func (u *upsample) Get() interface{} {
+	return *u
+}
+
+func (u *upsample) Blank() interface{} {
+	return u
+}
+
+func (u *upsample) OutputShape(inputs []*bs.Node) (tensors.Tensor, error) {
+	if err := u.setDims(inputs); err != nil {
+		return tensors.Tensor{}, err
+	}
+
+	return tensors.NewTensor(u.outs.Dims), nil
+}
+
+func (u *upsample) Evaluate(n *bs.Node, values []float64) {
+	inputs := n.AllInputs()
+
+	f := func(v int) {
+		ins, weights := u.sources(v)
+
+		var sum float64
+		for i, in := range ins {
+			sum += weights[i] * inputs[in]
+		}
+
+		values[v] = sum
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, len(values), f, opsPerThread, threadsPerCPU)
+}
+
+func (u *upsample) InputDeltas(n *bs.Node) []float64 {
+	atoms := make([]uint64, n.NumInputs())
+
+	f := func(out int) {
+		ins, weights := u.sources(out)
+		for i, in := range ins {
+			atomAdd(&atoms[in], weights[i]*n.Delta(out))
+		}
+	}
+
+	opsPerThread, threadsPerCPU := 1, 1
+	utils.MultiThread(0, n.Size(), f, opsPerThread, threadsPerCPU)
+
+	return uint64ToFloat64(atoms)
+}
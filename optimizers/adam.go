@@ -0,0 +1,147 @@
+package optimizers
+
+import (
+	bs "github.com/sharnoff/badstudent"
+	"math"
+)
+
+type adam struct {
+	// AMS is whether or not the AMSGrad variant is used
+	AMS bool
+
+	// States stores the moments for each Node the Optimizer has been run on, keyed by Node ID.
+	States map[int]*adamState
+
+	// Batches stores the gradients given for each Node over the current batch, keyed by Node ID.
+	// A Node is only present if it is in the middle of a batch. The moments are updated from
+	// these by EndBatch.
+	Batches map[int]*batchState
+}
+
+type adamState struct {
+	// the first and second moment estimates
+	M, V []float64
+
+	// the running maximum of V, only used by AMSGrad
+	VMax []float64
+
+	// the number of batches that have been completed, for bias correction
+	T int
+}
+
+// Adam returns the Adam Optimizer. Adam requires the hyperparameters "learning-rate", "beta1",
+// "beta2", and "epsilon". Typical values are 0.001, 0.9, 0.999, and 1e-8, respectively.
+//
+// The moments are updated once per batch, using the sum of the gradients over the batch, so
+// that each batch is a single step. The result of Adam implements badstudent.Optimizer,
+// badstudent.BatchOptimizer and badstudent.Storable; the moment estimates for each Node, along with
+// the gradients of any unfinished batch, are saved and loaded with the Network so that training
+// can be resumed.
+//
+// For more information, see: https://arxiv.org/abs/1412.6980
+func Adam() *adam {
+	return &adam{}
+}
+
+// AMSGrad returns the AMSGrad variant of Adam, which uses the maximum of all previous second
+// moment estimates instead of the current one. AMSGrad requires the same hyperparameters as Adam.
+//
+// For more information, see: https://openreview.net/forum?id=ryQu7f-RZ
+func AMSGrad() *adam {
+	return &adam{AMS: true}
+}
+
+func (a *adam) TypeString() string {
+	return "adam"
+}
+
+// state returns the stored moments for the given Node, allocating them if they do not exist or
+// do not match the number of weights
+func (a *adam) state(n *bs.Node, size int) *adamState {
+	if a.States == nil {
+		a.States = make(map[int]*adamState)
+	}
+
+	s := a.States[n.ID()]
+	if s == nil || len(s.M) != size {
+		s = &adamState{
+			M: make([]float64, size),
+			V: make([]float64, size),
+		}
+
+		if a.AMS {
+			s.VMax = make([]float64, size)
+		}
+
+		a.States[n.ID()] = s
+	}
+
+	return s
+}
+
+func (a *adam) Run(n *bs.Node, adj bs.Adjustable, ch []float64) {
+	η := n.HP("learning-rate")
+	β1, β2 := n.HP("beta1"), n.HP("beta2")
+	ε := n.HP("epsilon")
+
+	if a.Batches == nil {
+		a.Batches = make(map[int]*batchState)
+	}
+
+	s := a.state(n, len(ch))
+
+	// bias corrections, for the moments as they will be at the end of the batch
+	c1 := 1 - math.Pow(β1, float64(s.T+1))
+	c2 := 1 - math.Pow(β2, float64(s.T+1))
+
+	step := func(i int, g float64) float64 {
+		m, v := a.moments(s, i, g, β1, β2)
+		return -1 * η * (m / c1) / (math.Sqrt(v/c2) + ε)
+	}
+
+	runBatch(a.Batches, n, adj, ch, step)
+}
+
+// moments returns the first and second moment estimates of the weight at the given index, after
+// a batch where the sum of its gradients was g. With AMSGrad, the second moment is the maximum of
+// all of its estimates.
+func (a *adam) moments(s *adamState, i int, g, β1, β2 float64) (m, v float64) {
+	m = β1*s.M[i] + (1-β1)*g
+	v = β2*s.V[i] + (1-β2)*g*g
+
+	if a.AMS {
+		v = math.Max(s.VMax[i], v)
+	}
+
+	return m, v
+}
+
+func (a *adam) EndBatch(n *bs.Node) {
+	β1, β2 := n.HP("beta1"), n.HP("beta2")
+	s := a.States[n.ID()]
+
+	update := func(i int, g float64) {
+		s.M[i] = β1*s.M[i] + (1-β1)*g
+		s.V[i] = β2*s.V[i] + (1-β2)*g*g
+
+		if a.AMS {
+			s.VMax[i] = math.Max(s.VMax[i], s.V[i])
+		}
+	}
+
+	if endBatch(a.Batches, n, update) {
+		s.T++
+	}
+}
+
+func (a *adam) Needs() []string {
+	return []string{"learning-rate", "beta1", "beta2", "epsilon"}
+}
+
+func (a *adam) Save(dirPath string) error {
+	return saveState(dirPath, a)
+}
+
+func (a *adam) Load(dirPath string) error {
+	return loadState(dirPath, a)
+}
@@ -0,0 +1,261 @@
+// adaptive.go contains the Optimizers that scale the learning rate for each weight individually,
+// based on the history of its gradients:
+// * RMSProp
+// * Adagrad
+// * Adadelta
+//
+// Adam, which is also of this family, can be found in adam.go
+package optimizers
+
+import (
+	bs "github.com/sharnoff/badstudent"
+	"math"
+)
+
+// nodeBuffer returns the slice stored in 'm' for the given Node, allocating (and storing) a new
+// one if it does not exist or does not match the given size. 'm' must not be nil.
+func nodeBuffer(m map[int][]float64, n *bs.Node, size int) []float64 {
+	b := m[n.ID()]
+	if len(b) != size {
+		b = make([]float64, size)
+		m[n.ID()] = b
+	}
+
+	return b
+}
+
+// ****************************************
+// RMSProp
+// ****************************************
+
+type rmsProp struct {
+	// Squares stores the moving average of the squared gradients for each Node the Optimizer has
+	// been run on, keyed by Node ID.
+	Squares map[int][]float64
+
+	// Batches stores the gradients given for each Node over the current batch, keyed by Node ID.
+	// Squares is updated from these by EndBatch.
+	Batches map[int]*batchState
+}
+
+// RMSProp returns the RMSProp Optimizer, which divides the learning rate of each weight by a
+// moving average of the magnitude of its recent gradients. RMSProp requires the hyperparameters
+// "learning-rate", "rho", and "epsilon". Typical values are 0.001, 0.9, and 1e-8, respectively.
+//
+// The moving average is updated once per batch, using the sum of the gradients over the batch.
+// The result of RMSProp implements badstudent.Optimizer, badstudent.BatchOptimizer and
+// badstudent.Storable.
+func RMSProp() *rmsProp {
+	return &rmsProp{}
+}
+
+func (r *rmsProp) TypeString() string {
+	return "rmsprop"
+}
+
+func (r *rmsProp) Run(n *bs.Node, a bs.Adjustable, ch []float64) {
+	η := n.HP("learning-rate")
+	ρ := n.HP("rho")
+	ε := n.HP("epsilon")
+
+	if r.Squares == nil {
+		r.Squares = make(map[int][]float64)
+	}
+
+	if r.Batches == nil {
+		r.Batches = make(map[int]*batchState)
+	}
+
+	sq := nodeBuffer(r.Squares, n, len(ch))
+
+	step := func(i int, g float64) float64 {
+		return -1 * η * g / (math.Sqrt(ρ*sq[i]+(1-ρ)*g*g) + ε)
+	}
+
+	runBatch(r.Batches, n, a, ch, step)
+}
+
+func (r *rmsProp) EndBatch(n *bs.Node) {
+	ρ := n.HP("rho")
+	sq := r.Squares[n.ID()]
+
+	endBatch(r.Batches, n, func(i int, g float64) {
+		sq[i] = ρ*sq[i] + (1-ρ)*g*g
+	})
+}
+
+func (r *rmsProp) Needs() []string {
+	return []string{"learning-rate", "rho", "epsilon"}
+}
+
+func (r *rmsProp) Save(dirPath string) error {
+	return saveState(dirPath, r)
+}
+
+func (r *rmsProp) Load(dirPath string) error {
+	return loadState(dirPath, r)
+}
+
+// ****************************************
+// Adagrad
+// ****************************************
+
+type adagrad struct {
+	// Sums stores the sum of the squared gradients for each Node the Optimizer has been run on,
+	// keyed by Node ID.
+	Sums map[int][]float64
+
+	// Batches stores the gradients given for each Node over the current batch, keyed by Node ID.
+	// Sums is updated from these by EndBatch.
+	Batches map[int]*batchState
+}
+
+// Adagrad returns the Adagrad Optimizer, which divides the learning rate of each weight by the
+// square root of the sum of all of its previous squared gradients. Adagrad requires the
+// hyperparameters "learning-rate" and "epsilon". Typical values are 0.01 and 1e-8, respectively.
+//
+// The sums are of the squared gradient of each batch, which is the sum of the gradients over the
+// batch, so that the learning rate decreases with the number of batches, not samples. The result
+// of Adagrad implements badstudent.Optimizer, badstudent.BatchOptimizer and badstudent.Storable.
+func Adagrad() *adagrad {
+	return &adagrad{}
+}
+
+func (ad *adagrad) TypeString() string {
+	return "adagrad"
+}
+
+func (ad *adagrad) Run(n *bs.Node, a bs.Adjustable, ch []float64) {
+	η := n.HP("learning-rate")
+	ε := n.HP("epsilon")
+
+	if ad.Sums == nil {
+		ad.Sums = make(map[int][]float64)
+	}
+
+	if ad.Batches == nil {
+		ad.Batches = make(map[int]*batchState)
+	}
+
+	sum := nodeBuffer(ad.Sums, n, len(ch))
+
+	step := func(i int, g float64) float64 {
+		return -1 * η * g / (math.Sqrt(sum[i]+g*g) + ε)
+	}
+
+	runBatch(ad.Batches, n, a, ch, step)
+}
+
+func (ad *adagrad) EndBatch(n *bs.Node) {
+	sum := ad.Sums[n.ID()]
+
+	endBatch(ad.Batches, n, func(i int, g float64) {
+		sum[i] += g * g
+	})
+}
+
+func (ad *adagrad) Needs() []string {
+	return []string{"learning-rate", "epsilon"}
+}
+
+func (ad *adagrad) Save(dirPath string) error {
+	return saveState(dirPath, ad)
+}
+
+func (ad *adagrad) Load(dirPath string) error {
+	return loadState(dirPath, ad)
+}
+
+// ****************************************
+// Adadelta
+// ****************************************
+
+type adadelta struct {
+	// Squares stores the moving average of the squared gradients for each Node the Optimizer has
+	// been run on, keyed by Node ID.
+	Squares map[int][]float64
+
+	// Deltas stores the moving average of the squared changes to the weights, keyed in the same
+	// way as Squares.
+	Deltas map[int][]float64
+
+	// Batches stores the gradients given for each Node over the current batch, keyed by Node ID.
+	// Squares and Deltas are updated from these by EndBatch.
+	Batches map[int]*batchState
+}
+
+// Adadelta returns the Adadelta Optimizer, an extension of Adagrad that uses moving averages of
+// both the squared gradients and the squared changes to each weight, so that it does not need a
+// learning rate. Adadelta requires the hyperparameters "rho" and "epsilon". Typical values are
+// 0.95 and 1e-6, respectively.
+//
+// Both moving averages are updated once per batch, using the sum of the gradients over the batch.
+// The result of Adadelta implements badstudent.Optimizer, badstudent.BatchOptimizer and
+// badstudent.Storable.
+//
+// For more information, see: https://arxiv.org/abs/1212.5701
+func Adadelta() *adadelta {
+	return &adadelta{}
+}
+
+func (ad *adadelta) TypeString() string {
+	return "adadelta"
+}
+
+func (ad *adadelta) Run(n *bs.Node, a bs.Adjustable, ch []float64) {
+	ρ := n.HP("rho")
+	ε := n.HP("epsilon")
+
+	if ad.Squares == nil {
+		ad.Squares = make(map[int][]float64)
+	}
+
+	if ad.Deltas == nil {
+		ad.Deltas = make(map[int][]float64)
+	}
+
+	if ad.Batches == nil {
+		ad.Batches = make(map[int]*batchState)
+	}
+
+	sq := nodeBuffer(ad.Squares, n, len(ch))
+	ds := nodeBuffer(ad.Deltas, n, len(ch))
+
+	step := func(i int, g float64) float64 {
+		return ad.change(sq[i], ds[i], g, ρ, ε)
+	}
+
+	runBatch(ad.Batches, n, a, ch, step)
+}
+
+// change returns the change to a weight after a batch where the sum of its gradients was g, given
+// the moving averages of its squared gradients and squared changes before the batch
+func (ad *adadelta) change(sq, ds, g, ρ, ε float64) float64 {
+	sq = ρ*sq + (1-ρ)*g*g
+	return -1 * math.Sqrt(ds+ε) / math.Sqrt(sq+ε) * g
+}
+
+func (ad *adadelta) EndBatch(n *bs.Node) {
+	ρ := n.HP("rho")
+	ε := n.HP("epsilon")
+	sq, ds := ad.Squares[n.ID()], ad.Deltas[n.ID()]
+
+	endBatch(ad.Batches, n, func(i int, g float64) {
+		d := ad.change(sq[i], ds[i], g, ρ, ε)
+
+		sq[i] = ρ*sq[i] + (1-ρ)*g*g
+		ds[i] = ρ*ds[i] + (1-ρ)*d*d
+	})
+}
+
+func (ad *adadelta) Needs() []string {
+	return []string{"rho", "epsilon"}
+}
+
+func (ad *adadelta) Save(dirPath string) error {
+	return saveState(dirPath, ad)
+}
+
+func (ad *adadelta) Load(dirPath string) error {
+	return loadState(dirPath, ad)
+}
@@ -0,0 +1,79 @@
+package optimizers
+
+import (
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"runtime"
+)
+
+// batchState stores the gradients that an Optimizer has been given for a single Node over the
+// current batch, so that its statistics can be updated once per batch, from the sum of the
+// gradients, instead of once for each sample.
+type batchState struct {
+	// Grads is the sum of the gradients of each weight so far in the batch
+	Grads []float64
+
+	// Changes is the total change that has been given to each weight so far in the batch
+	Changes []float64
+
+	// Visited is whether or not each weight has been given a gradient during the batch. For
+	// Sparse Operators, this may not be all of them.
+	Visited []bool
+}
+
+// runBatch adds the gradients given by 'a' to the batch of the Node, stored in 'm' by Node ID.
+// For each weight that is visited, it adds to 'ch' so that the total change given over the batch
+// is equal to step(i, g), where g is the sum of the gradients of weight i so far. Because step may
+// be called many times per batch, it must not change the statistics of the Optimizer; those should
+// be updated by EndBatch, through endBatch.
+//
+// Without batching, EndBatch is called directly after each Run, so this is the same as applying
+// step to the gradient of each sample.
+func runBatch(m map[int]*batchState, n *bs.Node, a bs.Adjustable, ch []float64, step func(int, float64) float64) {
+	b := m[n.ID()]
+	if b == nil || len(b.Grads) != len(ch) {
+		b = &batchState{
+			Grads:   make([]float64, len(ch)),
+			Changes: make([]float64, len(ch)),
+			Visited: make([]bool, len(ch)),
+		}
+
+		m[n.ID()] = b
+	}
+
+	f := func(i int) {
+		b.Grads[i] += a.Grad(n, i)
+		b.Visited[i] = true
+
+		s := step(i, b.Grads[i])
+		ch[i] += s - b.Changes[i]
+		b.Changes[i] = s
+	}
+
+	eachWeight(n, len(ch), f)
+}
+
+// endBatch removes the batch of the Node from 'm', calling update in parallel with the index and
+// summed gradient of each weight that was visited during it. endBatch returns false, without
+// calling update, if the Node has not been given any gradients since the last batch ended.
+func endBatch(m map[int]*batchState, n *bs.Node, update func(int, float64)) bool {
+	b, ok := m[n.ID()]
+	if !ok {
+		return false
+	}
+
+	delete(m, n.ID())
+
+	f := func(i int) {
+		if b.Visited[i] {
+			update(i, b.Grads[i])
+		}
+	}
+
+	// just arbitrary constants
+	threadsPerCPU := 1
+	opsPerThread := runtime.NumCPU() * 2
+	utils.MultiThread(0, len(b.Grads), f, opsPerThread, threadsPerCPU)
+
+	return true
+}
@@ -28,12 +28,123 @@ func (s sgd) Run(n *bs.Node, a bs.Adjustable, ch []float64) {
 		ch[i] += -1 * η * a.Grad(n, i)
 	}
 
+	eachWeight(n, len(ch), f)
+}
+
+func (s sgd) Needs() []string {
+	return []string{"learning-rate"}
+}
+
+type momentum struct {
+	// Nesterov is whether or not Nesterov's accelerated gradient is used
+	Nesterov bool
+
+	// Velocities stores the velocity of each weight for each Node the Optimizer has been run on,
+	// keyed by Node ID.
+	Velocities map[int][]float64
+
+	// batches stores the velocities being built up over the current batch, keyed by Node ID. A
+	// Node is only present if it is in the middle of a batch. These are moved to Velocities by
+	// EndBatch.
+	batches map[int][]float64
+}
+
+// Momentum returns the gradient descent Optimizer with classical momentum. Momentum requires the
+// hyperparameters "learning-rate" > 0 and "momentum", where 0 ≤ momentum < 1. A typical value for
+// momentum is 0.9.
+//
+// The velocities are updated once per batch, using the sum of the gradients over the batch, so
+// batching behaves the same as it does with SGD. Because the velocities keep moving weights with
+// zero gradients, Momentum visits every weight, even for badstudent.Sparse Operators. The result of
+// Momentum implements badstudent.Optimizer, badstudent.BatchOptimizer and badstudent.Storable.
+func Momentum() *momentum {
+	return &momentum{}
+}
+
+// Nesterov returns the gradient descent Optimizer with Nesterov accelerated gradient. It requires
+// the same hyperparameters as Momentum.
+//
+// Instead of evaluating the gradient at the 'look-ahead' position, the equivalent reformulation
+// from Bengio et al. is used, so that the gradients given by Operators can be used directly. For
+// more information, see: https://arxiv.org/abs/1212.0901
+func Nesterov() *momentum {
+	return &momentum{Nesterov: true}
+}
+
+func (m *momentum) TypeString() string {
+	return "momentum"
+}
+
+func (m *momentum) Run(n *bs.Node, a bs.Adjustable, ch []float64) {
+	η := n.HP("learning-rate")
+	μ := n.HP("momentum")
+
+	if m.Velocities == nil {
+		m.Velocities = make(map[int][]float64)
+	}
+
+	if m.batches == nil {
+		m.batches = make(map[int][]float64)
+	}
+
+	v := m.Velocities[n.ID()]
+	if len(v) != len(ch) {
+		v = make([]float64, len(ch))
+		m.Velocities[n.ID()] = v
+	}
+
+	// If this is the first call in the batch, the decayed velocity from the previous batch must
+	// be added to the changes -- but only once.
+	newV, inBatch := m.batches[n.ID()]
+	if !inBatch {
+		newV = make([]float64, len(ch))
+		m.batches[n.ID()] = newV
+	}
+
+	// With Nesterov, the change applied is μ * v' - η * g, where v' = μ * v - η * g is the new
+	// velocity. Classical momentum applies v' directly.
+	factor := 1.0
+	if m.Nesterov {
+		factor = 1 + μ
+	}
+
+	f := func(i int) {
+		if !inBatch {
+			newV[i] = μ * v[i]
+
+			if m.Nesterov {
+				ch[i] += μ * newV[i]
+			} else {
+				ch[i] += newV[i]
+			}
+		}
+
+		g := a.Grad(n, i)
+		newV[i] += -1 * η * g
+		ch[i] += -1 * factor * η * g
+	}
+
 	// just arbitrary constants
 	threadsPerCPU := 1
 	opsPerThread := runtime.NumCPU() * 2
 	utils.MultiThread(0, len(ch), f, opsPerThread, threadsPerCPU)
 }
 
-func (s sgd) Needs() []string {
-	return []string{"learning-rate"}
+func (m *momentum) EndBatch(n *bs.Node) {
+	if newV, ok := m.batches[n.ID()]; ok {
+		m.Velocities[n.ID()] = newV
+		delete(m.batches, n.ID())
+	}
+}
+
+func (m *momentum) Needs() []string {
+	return []string{"learning-rate", "momentum"}
+}
+
+func (m *momentum) Save(dirPath string) error {
+	return saveState(dirPath, m)
+}
+
+func (m *momentum) Load(dirPath string) error {
+	return loadState(dirPath, m)
 }
@@ -5,6 +5,11 @@ import bs "github.com/sharnoff/badstudent"
 func init() {
 	list := []interface{}{
 		func() bs.Optimizer { return SGD() },
+		func() bs.Optimizer { return Adam() },
+		func() bs.Optimizer { return Momentum() },
+		func() bs.Optimizer { return RMSProp() },
+		func() bs.Optimizer { return Adagrad() },
+		func() bs.Optimizer { return Adadelta() },
 	}
 
 	if err := bs.RegisterAll(list); err != nil {
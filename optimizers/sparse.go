@@ -0,0 +1,30 @@
+package optimizers
+
+import (
+	bs "github.com/sharnoff/badstudent"
+	"github.com/sharnoff/badstudent/utils"
+	"runtime"
+)
+
+// eachWeight calls f in parallel with the index of each weight of the Node that needs to be
+// visited. If the Node is Sparse (as given by *Node.Touched), this is only the weights that have
+// been touched at the current iteration; otherwise, it is all of them.
+//
+// Optimizers that keep moving averages will not decay them for weights that are not touched, which
+// is the usual 'lazy' behavior for sparse gradients.
+func eachWeight(n *bs.Node, size int, f func(int)) {
+	// just arbitrary constants
+	threadsPerCPU := 1
+	opsPerThread := runtime.NumCPU() * 2
+
+	if idx, ok := n.Touched(); ok {
+		g := func(i int) {
+			f(idx[i])
+		}
+
+		utils.MultiThread(0, len(idx), g, opsPerThread, threadsPerCPU)
+		return
+	}
+
+	utils.MultiThread(0, size, f, opsPerThread, threadsPerCPU)
+}
@@ -0,0 +1,49 @@
+package optimizers
+
+import (
+	"encoding/json"
+	bs "github.com/sharnoff/badstudent"
+	"os"
+)
+
+// The name of the file, within the directory given to Save or Load, that Optimizers with internal
+// state are stored in.
+const stateFile string = "state.txt"
+
+// saveState writes the given value as JSON into a file inside dirPath, creating the directory if
+// necessary. It is the shared implementation of badstudent.Storable for Optimizers that keep
+// per-Node state.
+func saveState(dirPath string, v interface{}) error {
+	if err := os.MkdirAll(dirPath, 0700); err != nil {
+		return bs.FileError{Path: dirPath, Err: "Failed to create directory"}
+	}
+
+	path := dirPath + "/" + stateFile
+	f, err := os.Create(path)
+	if err != nil {
+		return bs.FileError{Path: path, Err: "Failed to create file"}
+	}
+	defer f.Close()
+
+	if err = json.NewEncoder(f).Encode(v); err != nil {
+		return bs.FileError{Path: path, Err: "Failed to encode JSON"}
+	}
+
+	return nil
+}
+
+// loadState is the counterpart to saveState. v should be a pointer.
+func loadState(dirPath string, v interface{}) error {
+	path := dirPath + "/" + stateFile
+	f, err := os.Open(path)
+	if err != nil {
+		return bs.FileError{Path: path, Err: "Failed to open file"}
+	}
+	defer f.Close()
+
+	if err = json.NewDecoder(f).Decode(v); err != nil {
+		return bs.FileError{Path: path, Err: "Failed to decode JSON"}
+	}
+
+	return nil
+}
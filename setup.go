@@ -116,13 +116,17 @@ func (err GetShapeError) Error() string {
 }
 
 type OperatorFinalizeError struct {
-	N *Node
+	N   *Node
 	Err error
+
+	// the Operator that was being finalized. This is not always N's Operator, because Replace
+	// finalizes it before it has been set
+	op Operator
 }
 
 func (err OperatorFinalizeError) Error() string {
 	return fmt.Sprintf("Failed to finalize operator <type: %s> for Node <id: %d>: %s",
-		err.N.op.TypeString(), err.N.id, err.Err.Error())
+		err.op.TypeString(), err.N.id, err.Err.Error())
 }
 
 // Add adds a new Node to the network with given Operator and inputs. Add returns the Node it
@@ -207,7 +211,7 @@ func (net *Network) Add(op Operator, inputs ...*Node) *Node {
 	n.lyr, n.elem, n.adj = castAll(op)
 
 	if err := n.op.Finalize(n); err != nil {
-		net.setError(OperatorFinalizeError{n, err})
+		net.setError(OperatorFinalizeError{N: n, Err: err, op: n.op})
 		return nil
 	}
 
@@ -406,7 +410,7 @@ func (n *Node) Replace(op Operator, inputs ...*Node) *Node {
 	lyr, elem, adj := castAll(op)
 
 	if err := op.Finalize(n); err != nil {
-		n.host.setError(OperatorFinalizeError{n, err})
+		n.host.setError(OperatorFinalizeError{N: n, Err: err, op: op})
 		return nil
 	}
 
@@ -469,7 +473,7 @@ func (net *Network) Finalize(cf CostFunction, outputs ...*Node) error {
 // "github.com/sharnoff/badstudent/initializers" is imported, because package initializers sets the
 // default package-wide
 type NoInitializerError struct {
-	N *Node
+	N   *Node
 }
 
 func (err NoInitializerError) Error() string {
@@ -561,6 +565,13 @@ func (net *Network) finalize(isLoading bool, cf CostFunction, outputs ...*Node)
 
 	net.cf = cf
 
+	// Stateful Operators make the Network recurrent, even without delay
+	for _, n := range net.nodesByID {
+		if _, ok := n.op.(Stateful); ok {
+			net.hasDelay = true
+		}
+	}
+
 	if net.defaultInit == nil {
 		net.defaultInit = defaultInitializer
 	}
@@ -577,7 +588,9 @@ func (net *Network) finalize(isLoading bool, cf CostFunction, outputs ...*Node)
 		// if it needs initializing
 		if n.adj != nil && !isLoading {
 
-			if net.defaultInit != nil {
+			if i, ok := n.op.(Initializer); ok {
+				i.Set(n, n.adj.Weights())
+			} else if net.defaultInit != nil {
 				net.defaultInit.Set(n, n.adj.Weights())
 			} else if defaultInitializer != nil {
 				defaultInitializer.Set(n, n.adj.Weights())
@@ -600,7 +613,7 @@ func (net *Network) finalize(isLoading bool, cf CostFunction, outputs ...*Node)
 // NilOptimizerError results from and documents Adjustable Nodes that have been given nil
 // Optimizers from a default Optimizer. This error (mostly) should not occur.
 type NilOptimizerError struct {
-	N *Node
+	N   *Node
 }
 
 func (err NilOptimizerError) Error() string {
@@ -610,7 +623,7 @@ func (err NilOptimizerError) Error() string {
 // MissingHyperParamError documents errors ocurring from missing hyperparameters that have not been
 // supplied to a certain Node.
 type MissingHyperParamError struct {
-	N *Node
+	N   *Node
 
 	// Name is the name of the missing HyperParameter
 	Name string
@@ -642,9 +655,21 @@ func (n *Node) checkHPs() error {
 	return nil
 }
 
+// WeightDecay is the name of the HyperParameter that sets the decoupled weight decay of a Node.
+// If it is present (from either the Node or the Network), the given fraction of each weight is
+// subtracted from it every time changes are added to the weights, independently of the Optimizer
+// and the gradient. This is in contrast to Penalties, which change the gradients given to the
+// Optimizer; weight decay through a Penalty does not regularize correctly with adaptive Optimizers
+// such as Adam.
+//
+// For more information, see: https://arxiv.org/abs/1711.05101
+const WeightDecay string = "weight-decay"
+
 // AddHP adds the given HyperParameter to the set of HyperParameters that will be supplied by the
 // Node to its Optimizer and Operator.
 //
+// Decoupled weight decay can be given to the Node with the name WeightDecay ("weight-decay").
+//
 // AddHP will panic if the Network has already been finalized. It will also return NilArgError if
 // the HyperParameter is nil, and ErrHPNameTaken if the name has already been registered to the
 // Node.
@@ -693,6 +718,8 @@ func (n *Node) ReplaceHP(name string, hp HyperParameter) error {
 
 // AddHP effectively performs the same operation as *Node.AddHP, but adds the HyperParameter to a
 // Network-wide list that Nodes will default to if that HyperParameter has not already been set.
+// Adding WeightDecay to the Network applies it to every Node with weights that does not have its
+// own.
 //
 // *Network.AddHP has the same error conditions as *Node.AddHP.
 func (net *Network) AddHP(name string, hp HyperParameter) *Network {
@@ -793,6 +820,75 @@ func (net *Network) SetPenalty(p Penalty) *Network {
 	return net
 }
 
+// ClipValue sets the maximum absolute value of the gradients given to the Node's Optimizer; any
+// gradient outside of [-limit, limit] will be clipped to that range. This overrides the value set
+// by *Network.ClipValue. ClipValue returns the Node it is called on so that methods can be chained.
+// If the Node's Operator is not Adjustable, ClipValue will have no measurable effect.
+//
+// ClipValue will panic with ErrNetFinalized if the Network has been finalized, and will set the
+// Network's error to ErrInvalidClip if limit ≤ 0.
+func (n *Node) ClipValue(limit float64) *Node {
+	if n == nil || n.host.Error() != nil {
+		return n
+	} else if n.host.stat >= finalized {
+		panic(ErrNetFinalized)
+	} else if limit <= 0 {
+		n.host.setError(ErrInvalidClip)
+		return n
+	}
+
+	n.clipValue = limit
+	return n
+}
+
+// ClipValue sets the default gradient value clipping for all Nodes in the Network. Nodes that
+// have had their own set by *Node.ClipValue will use that instead.
+//
+// ClipValue has the same error conditions as *Node.ClipValue, and will additionally panic with
+// ErrNilNet if the Network is nil.
+func (net *Network) ClipValue(limit float64) *Network {
+	if net == nil {
+		panic(ErrNilNet)
+	} else if net.Error() != nil {
+		return net
+	} else if net.stat >= finalized {
+		panic(ErrNetFinalized)
+	} else if limit <= 0 {
+		net.setError(ErrInvalidClip)
+		return net
+	}
+
+	net.clipValue = limit
+	return net
+}
+
+// ClipNorm sets the maximum global L2 norm of the gradients of all Adjustable Nodes in the
+// Network. Before any Optimizer is run, the norm of every gradient in the Network is calculated,
+// and if it is greater than max, all gradients are scaled down so that their norm is equal to
+// max. This happens at every iteration, including each time step of recurrent Networks.
+//
+// When training in batches, the gradients of each sample are clipped separately, before their
+// changes are added to those of the batch; the total gradient of the batch is not clipped.
+//
+// Global norm clipping is applied before value clipping, and after Penalties.
+//
+// ClipNorm has the same error conditions as *Network.ClipValue.
+func (net *Network) ClipNorm(max float64) *Network {
+	if net == nil {
+		panic(ErrNilNet)
+	} else if net.Error() != nil {
+		return net
+	} else if net.stat >= finalized {
+		panic(ErrNetFinalized)
+	} else if max <= 0 {
+		net.setError(ErrInvalidClip)
+		return net
+	}
+
+	net.clipNorm = max
+	return net
+}
+
 // SetDelay sets the number of time-steps in between calculation of the Node's values and those
 // calculated values becoming inputs for other Nodes. This will usually be set to 1.
 //
@@ -24,11 +24,17 @@ type Network struct {
 	hyperParams map[string]HyperParameter
 	pen         Penalty
 
+	// the thresholds for gradient clipping. clipValue is the default for Nodes that do not set
+	// their own, and clipNorm is the maximum L2 norm of the gradients across the whole Network.
+	// Zero indicates no clipping.
+	clipValue, clipNorm float64
+
 	// used to keep track of the current iteration during training. Also incremented by Correct
 	iter int
 
 	// longIter corresponds to the iteration of the network as a whole, not just within the current
-	// training run.
+	// training run. It is incremented alongside iter, so that HyperParameters change during
+	// training, and is saved with the Network so that they continue from the same point.
 	longIter int
 
 	// Whether or not there is the possibility of there being a loop in the passage of values from
@@ -39,9 +45,18 @@ type Network struct {
 	hasSavedChanges bool
 
 	// Whether or not there are any Nodes in the Network with delay. If there are, a different
-	// protocol must be followed
+	// protocol must be followed. Also set for Networks with Stateful Operators, and for those
+	// combined by Bidirectional
 	hasDelay bool
 
+	// Whether or not the Network is currently being evaluated as part of training, as opposed to
+	// testing or through GetOutputs
+	training bool
+
+	// Whether or not the deltas of the input Nodes are calculated, so that they can be given to
+	// another Network. Set by Bidirectional for its head Network
+	inputDeltas bool
+
 	stat status
 }
 
@@ -101,6 +116,10 @@ type Node struct {
 	opt Optimizer
 	pen Penalty
 
+	// the maximum absolute value of any gradient given to the Optimizer. Zero indicates that the
+	// Network's value should be used instead.
+	clipValue float64
+
 	// changes to the weights that have been delayed until the end of the batch
 	delayedWeights []float64
 
@@ -130,6 +149,10 @@ type Node struct {
 	delayDeltas  chan []float64
 	storedValues [][]float64
 
+	// nextValues are the values calculated by a Node with delay from its inputs at the current
+	// time step, which will be given as its values after the delay
+	nextValues []float64
+
 	// Whether or not the current task assigned by the Network has been completed. This value takes
 	// on different meanings depending on what the Network is doing. It is included as a sort of
 	// permenant auxiliary information storage.
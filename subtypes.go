@@ -109,6 +109,17 @@ type Adjustable interface {
 	Weights() []float64
 }
 
+// Sparse is an optional extension of Adjustable for Operators where only a few of the weights can
+// have non-zero gradients at each iteration, such as embedding tables. Optimizers can use
+// *Node.Touched to only visit those weights, instead of all of them.
+type Sparse interface {
+	Adjustable
+
+	// Touched returns the indexes of the weights that may have non-zero gradients for the current
+	// values of the Node. Each index should be given only once.
+	Touched(n *Node) []int
+}
+
 func isValid(o Operator) bool {
 	if _, ok := o.(Layer); ok {
 		return true
@@ -128,6 +139,45 @@ func castAll(o Operator) (l Layer, el Elementwise, adj Adjustable) {
 	return
 }
 
+// BatchOperator is an optional extension of Operator for those that keep statistics over each
+// batch of training data, such as batch normalization.
+type BatchOperator interface {
+	Operator
+
+	// EndBatch is called during training once the end of each batch has been reached, as given by
+	// DataSupplier.BatchEnded, after the weights of the Network have been updated. It is also
+	// called when training finishes, so that any partial batch is included, and so should do
+	// nothing if there have been no training samples since it was last called.
+	//
+	// If EndBatch returns an error, training stops, and Train returns it as type EndBatchError.
+	EndBatch(n *Node) error
+}
+
+// SequenceOperator is an optional extension of Operator for those that keep information over each
+// sequence in a recurrent Network, such as the masks of variational dropout.
+type SequenceOperator interface {
+	Operator
+
+	// EndSequence is called during training once the end of each sequence has been reached, as
+	// given by Sequential.SetEnded, after backpropagating through it. It is also called when
+	// training finishes.
+	EndSequence(n *Node)
+}
+
+// Stateful is an optional extension of Layer for those that carry their own state from one time
+// step to the next, such as fused recurrent cells, instead of through Nodes with delay. Networks
+// with a Stateful Node are recurrent, even if no Nodes have delay.
+//
+// While backpropagating, Stateful Layers are replayed in reverse order (see *Node.Replaying), and
+// must pass the deltas of their earlier states back through time themselves.
+type Stateful interface {
+	Layer
+
+	// ResetState sets the state back to how it was before the first time step. It is called
+	// between sequences, alongside *Network.ClearDelays.
+	ResetState(n *Node)
+}
+
 // Optimizer is an interface things that updates the weights of Adjustable
 // Operators
 type Optimizer interface {
@@ -148,6 +198,17 @@ type Optimizer interface {
 	Needs() []string
 }
 
+// BatchOptimizer is an optional extension of Optimizer for those that keep state between updates,
+// such as momentum. When training in batches, Run is called once for each sample before the
+// changes are added to the weights, so these Optimizers must be told when that happens.
+type BatchOptimizer interface {
+	Optimizer
+
+	// EndBatch is called once the changes made by Run have been added to the weights of the Node.
+	// Without batching, EndBatch is called directly after each Run.
+	EndBatch(n *Node)
+}
+
 // Penalty changes the gradients provided by Operators. Penalty's must be able to be called on
 // multiple different Nodes.
 type Penalty interface {
@@ -199,7 +260,26 @@ type HyperParameter interface {
 	Value(iter int) float64
 }
 
+// Observer is an optional extension of HyperParameter for those that change their values in
+// response to the progress of training, instead of just the iteration. During Network.Train, every
+// Result that is given to TrainArgs.Update is first given to each Observer in the Network. Test
+// results can be distinguished by Result.IsTest.
+//
+// Any state that an Observer keeps should be saved through JSONAble or Storable, so that it is
+// preserved when the Network is saved and loaded. HyperParameters that are built out of others
+// should pass Results on to any that are Observers.
+type Observer interface {
+	HyperParameter
+
+	// Observe is called with each Result from training, before the next iteration.
+	Observe(r Result)
+}
+
 // Initializer sets the initial weights in an Adjustable Operator.
+//
+// Adjustable Operators that have fixed starting weights (such as the scale and shift of
+// normalization layers) can implement Initializer themselves, in which case they will be used
+// instead of the Network's default Initializer.
 type Initializer interface {
 	Set(n *Node, weights []float64)
 }
@@ -2,6 +2,7 @@ package badstudent
 
 import (
 	"fmt"
+	"reflect"
 )
 
 // Datum is a simple type used to send training samples to the Network
@@ -107,7 +108,8 @@ type TrainArgs struct {
 	IsCorrect func([]float64, []float64) bool
 
 	// Update is how testing and status updates are returned. If both ShouldTest and SendData are
-	// nil, then Update can also be left nil.
+	// nil, then Update can also be left nil. Each Result is given to any HyperParameters that are
+	// Observers before it is given to Update.
 	Update func(Result)
 }
 
@@ -134,6 +136,18 @@ func (err GetDataError) Error() string {
 	return fmt.Sprintf("Failed to get"+test+"data. Iteration: %d. Error: %v.", err.Iteration, err.Err.Error())
 }
 
+// EndBatchError wraps errors from BatchOperator.EndBatch() during training
+type EndBatchError struct {
+	TrainContext
+
+	N   *Node
+	Err error
+}
+
+func (err EndBatchError) Error() string {
+	return fmt.Sprintf("Node %v failed to end batch. Iteration: %d. Error: %v.", err.N, err.Iteration, err.Err.Error())
+}
+
 // DoesNotFitError results from provided training/testing samples not fitting the dimensions of the
 // Network (i.e. number of inputs/outputs doesn't match) Note: This does not extend to cases where
 // no outputs are given to recurrent Networks in order to signify that they are inconsequential.
@@ -145,22 +159,28 @@ type DoesNotFitError struct {
 }
 
 func (err DoesNotFitError) Error() string {
+	return doesNotFit(err.TrainContext, err.Net.InputSize(), err.Net.OutputSize(), err.D)
+}
+
+// doesNotFit gives the message of DoesNotFitError and BiDoesNotFitError, given the sizes of the
+// inputs and outputs that were expected
+func doesNotFit(ctx TrainContext, inSize, outSize int, d Datum) string {
 	testData := "Data "
-	if err.FromTest {
+	if ctx.FromTest {
 		testData = "Test data "
 	}
 
 	var erroneous string
-	if len(err.D.Inputs) != err.Net.InputSize() {
-		erroneous += fmt.Sprintf(" Inputs expected %d, got %d.", err.Net.InputSize(), len(err.D.Inputs))
+	if len(d.Inputs) != inSize {
+		erroneous += fmt.Sprintf(" Inputs expected %d, got %d.", inSize, len(d.Inputs))
 	}
 
-	if len(err.D.Outputs) != 0 && len(err.D.Outputs) != err.Net.OutputSize() {
-		erroneous += fmt.Sprintf(" Outputs expected %d, got %d.", err.Net.OutputSize(), len(err.D.Outputs))
+	if len(d.Outputs) != 0 && len(d.Outputs) != outSize {
+		erroneous += fmt.Sprintf(" Outputs expected %d, got %d.", outSize, len(d.Outputs))
 	}
 
 	return fmt.Sprintf(testData+"from Iteration %d didn't match Network dimensions (Expected len in, out = %d, %d, got %d, %d).%s",
-		err.Iteration, err.Net.InputSize(), err.Net.OutputSize(), len(err.D.Inputs), len(err.D.Outputs), erroneous)
+		ctx.Iteration, inSize, outSize, len(d.Inputs), len(d.Outputs), erroneous)
 }
 
 // Train does what it says. It trains the Network following the conditions laid out in the
@@ -174,9 +194,10 @@ func (err DoesNotFitError) Error() string {
 //	(4) args.ShouldTest != nil but args.TestData == nil;
 //	(5) Failures to run TrainData.Get() or TestData.Get();
 //	(6) Data provided by Get() doesn't fit Network;
+//	(7) Failures of BatchOperator.EndBatch();
 // (0) and (1) return type NilArgError, (2) and (3) return ErrTrainNotSequential and
 // ErrTestNotSequential, respectively. (4) returns ErrShouldTestButNil, (5) gives type
-// GetdataError, and (6) returns type DoesNotFitError.
+// GetdataError, (6) returns type DoesNotFitError, and (7) gives type EndBatchError.
 func (net *Network) Train(args TrainArgs) error {
 	// handle error cases and set defaults
 	var trainSeq Sequential
@@ -217,9 +238,10 @@ func (net *Network) Train(args TrainArgs) error {
 		}
 	}
 
-	net.longIter += net.iter
 	net.iter = 0
 
+	observers := net.observers()
+
 	var statusCost, statusCorrect float64
 	var statusSize int
 
@@ -237,6 +259,10 @@ func (net *Network) Train(args TrainArgs) error {
 				IsTest:    false,
 			}
 
+			for _, o := range observers {
+				o.Observe(r)
+			}
+
 			args.Update(r)
 
 			statusCost, statusCorrect = 0, 0
@@ -259,6 +285,10 @@ func (net *Network) Train(args TrainArgs) error {
 					IsTest:    true,
 				}
 
+				for _, o := range observers {
+					o.Observe(r)
+				}
+
 				args.Update(r)
 			}
 		}
@@ -282,6 +312,7 @@ func (net *Network) Train(args TrainArgs) error {
 		// be the case (even with multithreading) because we just checked that.
 		//
 		// Therefore, we can discard the (im)possible error
+		net.training = true
 		outs, _ := net.GetOutputs(d.Inputs)
 
 		var cost float64
@@ -293,6 +324,7 @@ func (net *Network) Train(args TrainArgs) error {
 		}
 
 		endBatch := args.TrainData.BatchEnded(net.iter)
+		var batchErr error
 
 		if !net.hasDelay {
 			net.getDeltas(d.Outputs)
@@ -303,6 +335,10 @@ func (net *Network) Train(args TrainArgs) error {
 			if endBatch && net.hasSavedChanges {
 				net.AddWeights()
 			}
+
+			if endBatch {
+				batchErr = net.endBatch()
+			}
 		} else {
 			targets = append(targets, d.Outputs)
 
@@ -310,6 +346,14 @@ func (net *Network) Train(args TrainArgs) error {
 
 				// saveChanges = (endBatch || batchNext)
 				net.adjustRecurrent(targets, !(endBatch || batchNext))
+				net.endSequence()
+
+				// sequences are independent, so neither values nor deltas should carry over
+				net.ClearDelays()
+
+				if endBatch || batchNext {
+					batchErr = net.endBatch()
+				}
 
 				targets = nil
 				betweenSequences = true
@@ -319,6 +363,12 @@ func (net *Network) Train(args TrainArgs) error {
 			}
 		}
 
+		net.training = false
+
+		if batchErr != nil {
+			return batchErr
+		}
+
 		if len(d.Outputs) != 0 {
 			statusCost += cost
 			if correct {
@@ -328,22 +378,86 @@ func (net *Network) Train(args TrainArgs) error {
 		}
 
 		net.iter++
+		net.longIter++
 	}
 
 	// finish up before returning
+	var batchErr error
 	{
 		if net.hasSavedChanges {
 			net.AddWeights()
 		}
 
+		batchErr = net.endBatch()
+
 		if net.hasDelay {
+			net.endSequence()
 			net.ClearDelays()
 		}
 	}
 
+	return batchErr
+}
+
+// endBatch calls EndBatch for every Node in the Network with a BatchOperator, returning type
+// EndBatchError for the first that fails
+func (net *Network) endBatch() error {
+	for _, n := range net.nodesByID {
+		if b, ok := n.op.(BatchOperator); ok {
+			if err := b.EndBatch(n); err != nil {
+				return EndBatchError{TrainContext{net.iter, false}, n, err}
+			}
+		}
+	}
+
 	return nil
 }
 
+// endSequence calls EndSequence for every Node in the Network with a SequenceOperator
+func (net *Network) endSequence() {
+	for _, n := range net.nodesByID {
+		if s, ok := n.op.(SequenceOperator); ok {
+			s.EndSequence(n)
+		}
+	}
+}
+
+// observers returns every HyperParameter in the Network that is an Observer. Because Nodes may
+// share HyperParameters with each other and the Network, each Observer is only included once.
+func (net *Network) observers() []Observer {
+	var obs []Observer
+
+	add := func(hps map[string]HyperParameter) {
+		for _, hp := range hps {
+			if o, ok := hp.(Observer); ok {
+				obs = addObserver(obs, o)
+			}
+		}
+	}
+
+	add(net.hyperParams)
+	for _, n := range net.nodesByID {
+		add(n.hyperParams)
+	}
+
+	return obs
+}
+
+// addObserver appends the Observer to the list, unless it is already included. Observers whose
+// types are not comparable (such as structs containing slices) would cause a panic if they were
+// compared, so they are always assumed to be distinct.
+func addObserver(obs []Observer, o Observer) []Observer {
+	if reflect.TypeOf(o).Comparable() {
+		for _, other := range obs {
+			if other == o {
+				return obs
+			}
+		}
+	}
+
+	return append(obs, o)
+}
+
 // Test will test the Network on the supplied Data and function for determining whether or not the
 // outputs are correct. Test returns (in order) the average cost of the outputs and the percent of
 // the outputs that are correct.